@@ -0,0 +1,158 @@
+// Command history stores run summaries in a JSON-lines file and compares the
+// runs of different OCP and GPU operator versions.
+//
+//	go run ./cmd/history ingest -db history.jsonl <artifact dir or run-summary.json>...
+//	go run ./cmd/history compare -db history.jsonl -ocp 4.14 -from 23.6 -to 23.9
+//	go run ./cmd/history trend -db history.jsonl -ocp 4.14 -stage wait_for_gpu_operator
+//	go run ./cmd/history trend -db history.jsonl -ocp 4.14 -milestone driver_ready
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ci-tools-nvidia-gpu-operator/testutils/report"
+)
+
+const usage = `Usage: history <command> [flags]
+
+Commands:
+  ingest   add the runs of artifact dirs or run-summary.json files to the history
+  compare  compare the specs and stage durations of two sets of runs, as markdown
+  trend    print the duration of a stage or an install milestone in each run, as markdown
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "ingest":
+		err = ingest(os.Args[2:])
+	case "compare":
+		err = compare(os.Args[2:])
+	case "trend":
+		err = trend(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// readSummary reads the run summary of an artifact dir, generating it when
+// missing, or a run-summary.json file.
+func readSummary(path string) (*report.RunSummary, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		summaryFile := filepath.Join(path, report.SummaryFile)
+		if _, err := os.Stat(summaryFile); os.IsNotExist(err) {
+			return report.Generate(path)
+		}
+		path = summaryFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	summary := &report.RunSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, fmt.Errorf("invalid run summary %v: %v", path, err)
+	}
+	return summary, nil
+}
+
+func ingest(args []string) error {
+	flags := flag.NewFlagSet("ingest", flag.ExitOnError)
+	db := flags.String("db", "history.jsonl", "JSON-lines history file")
+	runID := flags.String("run-id", "", "ID of the run, defaults to its start time, versions and stage dirs (a single run only)")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no artifact dir or run summary to ingest")
+	}
+	if len(*runID) > 0 && flags.NArg() > 1 {
+		return fmt.Errorf("-run-id can only be set when ingesting a single run")
+	}
+	for _, path := range flags.Args() {
+		summary, err := readSummary(path)
+		if err != nil {
+			return err
+		}
+		record := report.NewHistoryRecord(summary, *runID)
+		added, err := report.AppendHistory(*db, record)
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Printf("=> Added run %v\n", record.RunID)
+		} else {
+			fmt.Printf("=> Run %v already in %v\n", record.RunID, *db)
+		}
+	}
+	return nil
+}
+
+func compare(args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	db := flags.String("db", "history.jsonl", "JSON-lines history file")
+	ocp := flags.String("ocp", "", "OCP version of both sides, e.g. 4.14")
+	channel := flags.String("channel", "", "GPU operator channel of both sides")
+	fromOperator := flags.String("from", "", "GPU operator version before, e.g. 23.6")
+	toOperator := flags.String("to", "", "GPU operator version after, e.g. 23.9")
+	fromOcp := flags.String("from-ocp", "", "OCP version before, overrides -ocp")
+	toOcp := flags.String("to-ocp", "", "OCP version after, overrides -ocp")
+	_ = flags.Parse(args)
+	from := report.HistoryFilter{OcpVersion: *ocp, OperatorVersion: *fromOperator, Channel: *channel}
+	to := report.HistoryFilter{OcpVersion: *ocp, OperatorVersion: *toOperator, Channel: *channel}
+	if len(*fromOcp) > 0 {
+		from.OcpVersion = *fromOcp
+	}
+	if len(*toOcp) > 0 {
+		to.OcpVersion = *toOcp
+	}
+	if from == to {
+		return fmt.Errorf("both sides select the same runs, set -from/-to or -from-ocp/-to-ocp")
+	}
+	records, err := report.ReadHistory(*db)
+	if err != nil {
+		return err
+	}
+	report.Compare(records, from, to).WriteMarkdown(os.Stdout)
+	return nil
+}
+
+func trend(args []string) error {
+	flags := flag.NewFlagSet("trend", flag.ExitOnError)
+	db := flags.String("db", "history.jsonl", "JSON-lines history file")
+	stage := flags.String("stage", "", "stage, e.g. wait_for_gpu_operator")
+	milestone := flags.String("milestone", "", "install milestone instead of a stage, e.g. driver_ready")
+	ocp := flags.String("ocp", "", "OCP version, e.g. 4.14")
+	operator := flags.String("operator", "", "GPU operator version, e.g. 23.9")
+	channel := flags.String("channel", "", "GPU operator channel")
+	_ = flags.Parse(args)
+	if len(*stage) == 0 && len(*milestone) == 0 {
+		return fmt.Errorf("-stage or -milestone is required")
+	}
+	records, err := report.ReadHistory(*db)
+	if err != nil {
+		return err
+	}
+	filter := report.HistoryFilter{OcpVersion: *ocp, OperatorVersion: *operator, Channel: *channel}
+	if len(*milestone) > 0 {
+		report.WriteMilestoneTrend(os.Stdout, report.FilterHistory(records, filter), *milestone)
+		return nil
+	}
+	report.WriteStageTrend(os.Stdout, report.FilterHistory(records, filter), *stage)
+	return nil
+}
@@ -0,0 +1,35 @@
+// Command report writes run-summary.json and run-summary.html into the
+// artifact dir, from the Ginkgo JSON reports of the stages run by
+// hack/run_test.sh.
+//
+//	go run ./cmd/report [artifact dir]
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ci-tools-nvidia-gpu-operator/testutils/report"
+)
+
+func main() {
+	artifactDir := os.Getenv("ARTIFACT_DIR")
+	if len(os.Args) > 1 {
+		artifactDir = os.Args[1]
+	}
+	if len(artifactDir) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: report <artifact dir>, or set ARTIFACT_DIR")
+		os.Exit(2)
+	}
+	summary, err := report.Generate(artifactDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate the run summary: %v\n", err)
+		os.Exit(1)
+	}
+	if err := report.Write(artifactDir, summary); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the run summary: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("=> Run summary written to %v\n", filepath.Join(artifactDir, report.HtmlFile))
+}
@@ -20,9 +20,12 @@ function deploy_nfd_operator() {
     print_test_title "${FUNCNAME[0]}"
     test_ocp_connection
     echo
-    echo "=> Deploying NFD Operator"
+    echo "=> Deploying NFD (${NFD_DEPLOY_MODE:-olm})"
     echo
     ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    export NFD_PCI_DEVICE_CLASSES="${PCI_DEVICE_CLASSES:-}"
+    export NFD_PCI_LABEL_FIELDS="${PCI_LABEL_FIELDS:-}"
+    export NFD_LABEL_SOURCES="${LABEL_SOURCES:-}"
     GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
 	ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./setup/ || error_and_exit "${FUNCNAME[0]} Test Failed." 2
 }
@@ -36,18 +39,37 @@ function deploy_gpu_operator() {
     echo
     ART_DIR=$(dirgen "${FUNCNAME[0]}")
     GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
-	ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./setup/ || error_and_exit "${FUNCNAME[0]} Test Failed." 3
+	(ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./setup/ && create_dashboard_operator_channel "${ART_DIR}") || error_and_exit "${FUNCNAME[0]} Test Failed." 3
 }
 
-function scale_aws_gpu_nodes() {
+function scale_gpu_nodes() {
     print_test_title "${FUNCNAME[0]}"
     ART_DIR=$(dirgen "${FUNCNAME[0]}")
     export GPU_INSTANCE_TYPE="${INSTANCE_TYPE:-}"
     export GPU_REPLICAS="${REPLICAS:-}"
+    export GPU_ACCELERATOR_TYPE="${ACCELERATOR_TYPE:-}"
+    export GPU_ACCELERATOR_COUNT="${ACCELERATOR_COUNT:-}"
+    export GPU_NODE_TAINTS="${NODE_TAINTS:-}"
+    export GPU_NODE_LABELS="${NODE_LABELS:-}"
+    export GPU_ZONE="${ZONE:-}"
+    export GPU_SPOT="${SPOT:-}"
+    export GPU_ROOT_VOLUME_SIZE="${ROOT_VOLUME_SIZE:-}"
     GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
     ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./setup/ || error_and_exit "${FUNCNAME[0]} Test Failed." 4 "$1"
 }
 
+function delete_gpu_nodes() {
+    print_test_title "${FUNCNAME[0]}"
+    ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
+    ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./setup/ || error_and_exit "${FUNCNAME[0]} Test Failed." 20
+}
+
+# Deprecated, scale_gpu_nodes detects the platform
+function scale_aws_gpu_nodes() {
+    scale_gpu_nodes "$@"
+}
+
 function deploy_gpu_operator_master() {
     print_test_title "${FUNCNAME[0]}"
     ART_DIR=$(dirgen "${FUNCNAME[0]}")
@@ -92,6 +114,10 @@ function wait_for_gpu_operator() {
 function run_gpu_workload() {
     print_test_title "${FUNCNAME[0]}"
     ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    export GPU_WORKLOAD="${WORKLOAD:-${GPU_WORKLOAD:-}}"
+    export GPU_WORKLOAD_IMAGE="${WORKLOAD_IMAGE:-${GPU_WORKLOAD_IMAGE:-}}"
+    export GPU_WORKLOAD_MODE="${WORKLOAD_MODE:-${GPU_WORKLOAD_MODE:-}}"
+    export GPU_BURN_DURATION="${BURN_DURATION:-${GPU_BURN_DURATION:-}}"
     GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
     ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 12
 }
@@ -125,6 +151,41 @@ function gpu_addon_must_gather() {
     ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 16
 }
 
+function gpu_operator_must_gather() {
+    print_test_title "${FUNCNAME[0]}"
+    ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
+    ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 19
+}
+
+function test_gpu_workload_scheduling() {
+    print_test_title "${FUNCNAME[0]}"
+    ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
+    ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 17
+}
+
+function test_gpu_operator_alerts() {
+    print_test_title "${FUNCNAME[0]}"
+    ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
+    ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 18
+}
+
+function test_gpu_autoscaling() {
+    print_test_title "${FUNCNAME[0]}"
+    ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
+    ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 21
+}
+
+function test_gfd_labels() {
+    print_test_title "${FUNCNAME[0]}"
+    ART_DIR=$(dirgen "${FUNCNAME[0]}")
+    GINKGO_ARGS=$(ginko_args "${ART_DIR}" "${FUNCNAME[0]}")
+    ARTIFACT_DIR=$ART_DIR ginkgo ${GINKGO_ARGS} ./tests/ || error_and_exit "${FUNCNAME[0]} Test Failed." 22
+}
+
 ########################
 ## General  functions ##
 ########################
@@ -136,7 +197,9 @@ function clean_artifact_dir() {
     rm -rf "${ARTIFACT_DIR}"/*.log
     rm -rf "${ARTIFACT_DIR}"/*.xml
     rm -rf "${ARTIFACT_DIR}"/*.version
+    rm -rf "${ARTIFACT_DIR}"/*.channel
     rm -rf "${ARTIFACT_DIR}"/*.git_version
+    rm -rf "${ARTIFACT_DIR}"/run-summary.*
     ls -d "${ARTIFACT_DIR}"/* | grep -P "[0-9]{10}_" | xargs  rm -rf
 }
 
@@ -158,6 +221,18 @@ function error_and_exit() {
     exit "$2"
 }
 
+function generate_report() {
+    # The report is informative, it must not change the result of the run
+    go run ./cmd/report "${ARTIFACT_DIR}" || echo "=> Failed to generate the run summary"
+}
+
+function generate_report_if_enabled() {
+    # Opt-in at the end of each target, the CI sets GENERATE_REPORT=true
+    if [[ "${GENERATE_REPORT:-false}" == "true" ]]; then
+        generate_report
+    fi
+}
+
 function dirgen() {
     timestamp=$(date +%s)
     dir="${ARTIFACT_DIR}/${timestamp}_${1}"
@@ -169,7 +244,7 @@ function ginko_args() {
     ART_DIR="$1"
     shift
     NAME="$1"
-    echo "--output-dir=$ART_DIR --junit-report=junit_report_${NAME}.xml --fail-fast --succinct --no-color --focus $NAME"
+    echo "--output-dir=$ART_DIR --junit-report=junit_report_${NAME}.xml --json-report=report_${NAME}.json --fail-fast --succinct --no-color --focus $NAME"
 }
 
 ### Init output folder
@@ -183,6 +258,13 @@ function create_dashboard_ocp_version() {
     connection_dir=$1
     tail -1 "${connection_dir}/OCP_Version.txt" | tr ' ' '\n' | tail -1 > "${ARTIFACT_DIR}/ocp.version"
 }
+function create_dashboard_operator_channel() {
+    deploy_gpu_operator_dir=$1
+    # Not written when deployed from a bundle
+    if [[ -f "${deploy_gpu_operator_dir}/gpu_operator_channel.txt" ]]; then
+        cp "${deploy_gpu_operator_dir}/gpu_operator_channel.txt" "${ARTIFACT_DIR}/operator.channel"
+    fi
+}
 function create_dashboard_operator_version() {
     wait_for_gpu_operator_dir=$1
     cp "${wait_for_gpu_operator_dir}/gpu_operator_version.txt" "${ARTIFACT_DIR}/operator.version"
@@ -202,7 +284,9 @@ case "$1" in
     test_ocp_connection) "$@" | tee -a "${OUTPUT_FILE}";;
     deploy_nfd_operator) "$@" | tee -a "${OUTPUT_FILE}";;
     deploy_gpu_operator) "$@" | tee -a "${OUTPUT_FILE}";;
+    scale_gpu_nodes) "$@" | tee -a "${OUTPUT_FILE}";;
     scale_aws_gpu_nodes) "$@" | tee -a "${OUTPUT_FILE}";;
+    delete_gpu_nodes) "$@" | tee -a "${OUTPUT_FILE}";;
     deploy_gpu_from_bundle) "$@" | tee -a "${OUTPUT_FILE}";;
     deploy_gpu_operator_master) "$@" | tee -a "${OUTPUT_FILE}";;
     ocm_addons_setup) "$@" | tee -a "${OUTPUT_FILE}";;
@@ -216,8 +300,14 @@ case "$1" in
     run_gpu_workload) "$@" | tee -a "${OUTPUT_FILE}";;
     check_exported_metrics) "$@" | tee -a "${OUTPUT_FILE}";;
     gpu_addon_must_gather) "$@" | tee -a "${OUTPUT_FILE}";;
+    gpu_operator_must_gather) "$@" | tee -a "${OUTPUT_FILE}";;
+    test_gpu_workload_scheduling) "$@" | tee -a "${OUTPUT_FILE}";;
+    test_gpu_operator_alerts) "$@" | tee -a "${OUTPUT_FILE}";;
+    test_gpu_autoscaling) "$@" | tee -a "${OUTPUT_FILE}";;
+    test_gfd_labels) "$@" | tee -a "${OUTPUT_FILE}";;
 
     clean_artifact_dir) "$@";exit;;
+    generate_report) "$@";exit;;
     *) error_and_exit "Invalid operation $1." 44 | tee -a "${OUTPUT_FILE}";;
 esac
 
@@ -227,7 +317,9 @@ mv "${ARTIFACT_DIR}"/*/*.xml "${ARTIFACT_DIR}"
 if [[ ! -f "${ARTIFACT_DIR}/FAIL" ]]; then
     echo "SUCCESS" > "${ARTIFACT_DIR}/SUCCESS"
     echo 0 > "${ARTIFACT_DIR}/RETURN_CODE"
+    generate_report_if_enabled | tee -a "${OUTPUT_FILE}"
 else
+    generate_report_if_enabled | tee -a "${OUTPUT_FILE}"
     exit $(cat "${ARTIFACT_DIR}/RETURN_CODE")
 fi
 
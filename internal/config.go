@@ -8,24 +8,97 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// NFD_DEPLOY_MODE values, upstream deploys the NFD manifests on clusters
+// without the NFD operator in their catalog
+const (
+	NfdDeployModeOlm      = "olm"
+	NfdDeployModeUpstream = "upstream"
+)
+
+// The upstream NFD release deployed in upstream mode, with its namespace and
+// operands. The image tag of the setup manifests must match the version.
+const (
+	UpstreamNfdVersion   = "v0.14.6"
+	UpstreamNfdNamespace = "node-feature-discovery"
+	UpstreamNfdMaster    = "nfd-master"
+	UpstreamNfdWorker    = "nfd-worker"
+)
+
+// Namespaces of the NFD operator, of the machine API and of the workloads of
+// the suites
+const (
+	NfdOperatorNamespace    = "openshift-nfd"
+	MachineApiNamespace     = "openshift-machine-api"
+	GpuBurnNamespace        = "gpu-burn-test"
+	GpuSchedulingNamespace  = "gpu-scheduling-test"
+	GpuAutoscalingNamespace = "gpu-autoscaling-test"
+)
+
+// EventNamespaces are the namespaces whose events the suites record: the
+// operators, the workloads, and the machine API for GPU node scaling. A suite
+// creating a namespace adds it here.
+func EventNamespaces() []string {
+	return []string{
+		Config.NameSpace,
+		NfdOperatorNamespace,
+		UpstreamNfdNamespace,
+		GpuBurnNamespace,
+		GpuSchedulingNamespace,
+		GpuAutoscalingNamespace,
+		MachineApiNamespace,
+	}
+}
+
 type config struct {
-	NameSpace                string
-	GpuOperatorChannel       string
-	KubeconfigPath           string
-	ArtifactDir              string
-	CiMachineSetInstanceType string
-	CiMachineSetReplicas     string
-	ClientConfig             *rest.Config
+	NameSpace                    string
+	GpuOperatorChannel           string
+	KubeconfigPath               string
+	ArtifactDir                  string
+	CiMachineSetInstanceType     string
+	CiMachineSetReplicas         string
+	CiMachineSetAcceleratorType  string
+	CiMachineSetAcceleratorCount string
+	CiMachineSetTaints           string
+	CiMachineSetNodeLabels       string
+	CiMachineSetZone             string
+	CiMachineSetSpot             string
+	CiMachineSetRootVolumeSize   string
+	GpuWorkload                  string
+	GpuWorkloadImage             string
+	GpuWorkloadMode              string
+	GpuBurnDuration              string
+	MilestoneBudgets             string
+	NfdPciDeviceClasses          string
+	NfdPciLabelFields            string
+	NfdLabelSources              string
+	NfdDeployMode                string
+	ClientConfig                 *rest.Config
 }
 
 var Config = config{
-	NameSpace:                GetVarDefault("WORKING_NAMESPACE", "nvidia-gpu-operator"),
-	GpuOperatorChannel:       GetVarDefault("GPU_CHANNEL", ""),
-	KubeconfigPath:           GetVarDefault("KUBECONFIG", ".kubeconfig"),
-	ArtifactDir:              GetVarDefault("ARTIFACT_DIR", "/tmp/gpu-test"),
-	CiMachineSetInstanceType: GetVarDefault("GPU_INSTANCE_TYPE", "g4dn.xlarge"),
-	CiMachineSetReplicas:     GetVarDefault("GPU_REPLICAS", "1"),
-	ClientConfig:             GetClientConfig(),
+	NameSpace:                    GetVarDefault("WORKING_NAMESPACE", "nvidia-gpu-operator"),
+	GpuOperatorChannel:           GetVarDefault("GPU_CHANNEL", ""),
+	KubeconfigPath:               GetVarDefault("KUBECONFIG", ".kubeconfig"),
+	ArtifactDir:                  GetVarDefault("ARTIFACT_DIR", "/tmp/gpu-test"),
+	CiMachineSetInstanceType:     GetVarDefault("GPU_INSTANCE_TYPE", ""),
+	CiMachineSetReplicas:         GetVarDefault("GPU_REPLICAS", "1"),
+	CiMachineSetAcceleratorType:  GetVarDefault("GPU_ACCELERATOR_TYPE", "nvidia-tesla-t4"),
+	CiMachineSetAcceleratorCount: GetVarDefault("GPU_ACCELERATOR_COUNT", "1"),
+	CiMachineSetTaints:           GetVarDefault("GPU_NODE_TAINTS", ""),
+	CiMachineSetNodeLabels:       GetVarDefault("GPU_NODE_LABELS", ""),
+	CiMachineSetZone:             GetVarDefault("GPU_ZONE", ""),
+	CiMachineSetSpot:             GetVarDefault("GPU_SPOT", ""),
+	CiMachineSetRootVolumeSize:   GetVarDefault("GPU_ROOT_VOLUME_SIZE", ""),
+	GpuWorkload:                  GetVarDefault("GPU_WORKLOAD", "gpu-burn"),
+	GpuWorkloadImage:             GetVarDefault("GPU_WORKLOAD_IMAGE", ""),
+	GpuWorkloadMode:              GetVarDefault("GPU_WORKLOAD_MODE", "job"),
+	GpuBurnDuration:              GetVarDefault("GPU_BURN_DURATION", "300"),
+	MilestoneBudgets:             GetVarDefault("MILESTONE_BUDGETS", ""),
+	NfdPciDeviceClasses:          GetVarDefault("NFD_PCI_DEVICE_CLASSES", ""),
+	NfdPciLabelFields:            GetVarDefault("NFD_PCI_LABEL_FIELDS", ""),
+	NfdLabelSources:              GetVarDefault("NFD_LABEL_SOURCES", ""),
+	NfdDeployMode:                GetVarDefault("NFD_DEPLOY_MODE", NfdDeployModeOlm),
+	ClientConfig:                 GetClientConfig(),
 }
 
 func GetVarDefault(evar string, _default string) string {
@@ -0,0 +1,186 @@
+package ocputils
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// ClusterAutoscalerName is the only name accepted for the ClusterAutoscaler
+	ClusterAutoscalerName = "default"
+	// AcceleratorLabel is the node label the cluster autoscaler uses to find
+	// the GPU nodes, its value is the type of the GPU resource limits.
+	AcceleratorLabel = "cluster-api/accelerator"
+	// MachineSetGpuAnnotation is the GPU count of the instance type, set by the
+	// Machine API. The cluster autoscaler scales MachineSets from zero with
+	// the capacity annotations.
+	MachineSetGpuAnnotation = "machine.openshift.io/GPU"
+)
+
+// MachineSetCapacityAnnotations are set by the Machine API from the instance
+// type of the MachineSet.
+var MachineSetCapacityAnnotations = []string{
+	"machine.openshift.io/vCPU",
+	"machine.openshift.io/memoryMb",
+	"machine.openshift.io/maxPods",
+	MachineSetGpuAnnotation,
+}
+
+// The ClusterAutoscaler and MachineAutoscaler API versions, see Scheme
+var (
+	clusterAutoscalerGroupVersion = schema.GroupVersion{Group: "autoscaling.openshift.io", Version: "v1"}
+	machineAutoscalerGroupVersion = schema.GroupVersion{Group: "autoscaling.openshift.io", Version: "v1beta1"}
+)
+
+// ClusterAutoscaler holds the fields of an autoscaling.openshift.io/v1 ClusterAutoscaler.
+type ClusterAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ClusterAutoscalerSpec `json:"spec"`
+}
+
+type ClusterAutoscalerSpec struct {
+	ResourceLimits *ResourceLimits `json:"resourceLimits,omitempty"`
+	ScaleDown      *ScaleDown      `json:"scaleDown,omitempty"`
+}
+
+type ResourceLimits struct {
+	GPUs []GPULimit `json:"gpus,omitempty"`
+}
+
+// GPULimit limits the GPUs of Type, the value of the AcceleratorLabel of the nodes.
+type GPULimit struct {
+	Type string `json:"type"`
+	Min  int32  `json:"min"`
+	Max  int32  `json:"max"`
+}
+
+type ScaleDown struct {
+	Enabled           bool   `json:"enabled"`
+	DelayAfterAdd     string `json:"delayAfterAdd,omitempty"`
+	DelayAfterDelete  string `json:"delayAfterDelete,omitempty"`
+	DelayAfterFailure string `json:"delayAfterFailure,omitempty"`
+	UnneededTime      string `json:"unneededTime,omitempty"`
+}
+
+// MachineAutoscaler holds the fields of an autoscaling.openshift.io/v1beta1 MachineAutoscaler.
+type MachineAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              MachineAutoscalerSpec `json:"spec"`
+}
+
+type MachineAutoscalerSpec struct {
+	MinReplicas    int32                       `json:"minReplicas"`
+	MaxReplicas    int32                       `json:"maxReplicas"`
+	ScaleTargetRef CrossVersionObjectReference `json:"scaleTargetRef"`
+}
+
+type CrossVersionObjectReference struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+func CreateClusterAutoscaler(config *rest.Config, ca *ClusterAutoscaler) (*ClusterAutoscaler, error) {
+	return Create(config, ca)
+}
+
+func GetClusterAutoscaler(config *rest.Config, name string) (*ClusterAutoscaler, error) {
+	return Get[ClusterAutoscaler](config, "", name)
+}
+
+func DeleteClusterAutoscaler(config *rest.Config, name string) error {
+	return Delete(config, &ClusterAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: name}})
+}
+
+// NewMachineAutoscaler returns a MachineAutoscaler scaling the MachineSet
+// between min and max replicas.
+func NewMachineAutoscaler(namespace string, machineSet string, min int32, max int32) *MachineAutoscaler {
+	return &MachineAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      machineSet,
+			Namespace: namespace,
+		},
+		Spec: MachineAutoscalerSpec{
+			MinReplicas: min,
+			MaxReplicas: max,
+			ScaleTargetRef: CrossVersionObjectReference{
+				APIVersion: "machine.openshift.io/v1beta1",
+				Kind:       "MachineSet",
+				Name:       machineSet,
+			},
+		},
+	}
+}
+
+// EnsureMachineAutoscaler creates the MachineAutoscaler, or updates the one
+// left by a previous run. It returns the conflict when another manager owns
+// the fields of ma.
+func EnsureMachineAutoscaler(config *rest.Config, ma *MachineAutoscaler) (*MachineAutoscaler, error) {
+	return Ensure(config, ma, false)
+}
+
+func GetMachineAutoscaler(config *rest.Config, namespace string, name string) (*MachineAutoscaler, error) {
+	return Get[MachineAutoscaler](config, namespace, name)
+}
+
+func DeleteMachineAutoscaler(config *rest.Config, namespace string, name string) error {
+	return Delete(config, &MachineAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}})
+}
+
+// The autoscaling API is not vendored, the deep copy functions are written
+// as deepcopy-gen would generate them.
+
+func (in *ClusterAutoscaler) DeepCopyInto(out *ClusterAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec.ResourceLimits != nil {
+		limits := &ResourceLimits{}
+		if in.Spec.ResourceLimits.GPUs != nil {
+			limits.GPUs = make([]GPULimit, len(in.Spec.ResourceLimits.GPUs))
+			copy(limits.GPUs, in.Spec.ResourceLimits.GPUs)
+		}
+		out.Spec.ResourceLimits = limits
+	}
+	if in.Spec.ScaleDown != nil {
+		scaleDown := *in.Spec.ScaleDown
+		out.Spec.ScaleDown = &scaleDown
+	}
+}
+
+func (in *ClusterAutoscaler) DeepCopy() *ClusterAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ClusterAutoscaler) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *MachineAutoscaler) DeepCopyInto(out *MachineAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+func (in *MachineAutoscaler) DeepCopy() *MachineAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *MachineAutoscaler) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
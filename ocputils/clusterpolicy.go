@@ -0,0 +1,25 @@
+package ocputils
+
+import (
+	"fmt"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// GetClusterPolicy returns the ClusterPolicy of the cluster, there is only one.
+func GetClusterPolicy(config *rest.Config) (*gpuv1.ClusterPolicy, error) {
+	clusterPolicies, err := List[gpuv1.ClusterPolicy](config, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(clusterPolicies) != 1 {
+		return nil, fmt.Errorf("expected a single ClusterPolicy, found %v", len(clusterPolicies))
+	}
+	return &clusterPolicies[0], nil
+}
+
+func PatchClusterPolicy(config *rest.Config, name string, data []byte, pt types.PatchType) (*gpuv1.ClusterPolicy, error) {
+	return Patch[gpuv1.ClusterPolicy](config, "", name, data, pt)
+}
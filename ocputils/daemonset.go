@@ -1,26 +1,31 @@
 package ocputils
 
 import (
-	"context"
-
 	appsv1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 func GetDaemonset(config *rest.Config, namespace string, name string) (*appsv1.DaemonSet, error) {
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return clientset.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	return Get[appsv1.DaemonSet](config, namespace, name)
 }
 
-func CreatDaemonSet(config *rest.Config, ds *appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
-	clientset, err := kubernetes.NewForConfig(config)
+func CreateDaemonSet(config *rest.Config, ds *appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
+	return Create(config, ds)
+}
+
+func GetDaemonsets(config *rest.Config, namespace string) (*appsv1.DaemonSetList, error) {
+	items, err := List[appsv1.DaemonSet](config, namespace, "")
 	if err != nil {
 		return nil, err
 	}
-	return clientset.AppsV1().DaemonSets(ds.Namespace).Create(context.TODO(), ds, metav1.CreateOptions{})
+	return &appsv1.DaemonSetList{Items: items}, nil
+}
+
+// DaemonSetReady returns whether the pods of the latest generation of the
+// daemonset are available on all its nodes.
+func DaemonSetReady(ds *appsv1.DaemonSet) bool {
+	return ds.Status.ObservedGeneration >= ds.Generation &&
+		ds.Status.DesiredNumberScheduled > 0 &&
+		ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled
 }
@@ -0,0 +1,22 @@
+package ocputils
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/rest"
+)
+
+func GetDeployment(config *rest.Config, namespace string, name string) (*appsv1.Deployment, error) {
+	return Get[appsv1.Deployment](config, namespace, name)
+}
+
+// DeploymentReady returns whether all the replicas of the latest generation
+// of the deployment are available.
+func DeploymentReady(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+}
@@ -0,0 +1,37 @@
+package ocputils
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+func CreateJob(config *rest.Config, job *batchv1.Job) (*batchv1.Job, error) {
+	return Create(config, job)
+}
+
+func GetJob(config *rest.Config, namespace string, name string) (*batchv1.Job, error) {
+	return Get[batchv1.Job](config, namespace, name)
+}
+
+func GetJobsByLabel(config *rest.Config, namespace string, labelSelector string) (*batchv1.JobList, error) {
+	items, err := List[batchv1.Job](config, namespace, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	return &batchv1.JobList{Items: items}, nil
+}
+
+// GetJobFinishedCondition returns the Complete or Failed condition of a
+// Job, or nil while the Job is still running.
+func GetJobFinishedCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
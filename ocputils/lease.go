@@ -0,0 +1,27 @@
+package ocputils
+
+import (
+	"fmt"
+	"strings"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/client-go/rest"
+)
+
+func GetLease(config *rest.Config, namespace string, name string) (*coordinationv1.Lease, error) {
+	return Get[coordinationv1.Lease](config, namespace, name)
+}
+
+// GetLeaderPodName returns the name of the pod holding a leader election
+// Lease of controller-runtime, whose holder identity is <hostname>_<uuid>.
+func GetLeaderPodName(config *rest.Config, namespace string, name string) (string, error) {
+	lease, err := GetLease(config, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	if lease.Spec.HolderIdentity == nil || len(*lease.Spec.HolderIdentity) == 0 {
+		return "", fmt.Errorf("lease %v/%v has no holder", namespace, name)
+	}
+	podName, _, _ := strings.Cut(*lease.Spec.HolderIdentity, "_")
+	return podName, nil
+}
@@ -0,0 +1,105 @@
+package ocputils
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// MachineFailure is a Machine that the Machine API failed to provision, e.g.
+// with an InsufficientInstanceCapacity error from the cloud provider. It does
+// not recover by waiting.
+type MachineFailure struct {
+	Machine string `json:"machine"`
+	Phase   string `json:"phase,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (f MachineFailure) String() string {
+	return fmt.Sprintf("machine %v phase=%v reason=%v: %v", f.Machine, f.Phase, f.Reason, f.Message)
+}
+
+// MachineSetFailedError is returned while some Machines of the MachineSet failed.
+type MachineSetFailedError struct {
+	MachineSet string
+	Failures   []MachineFailure
+}
+
+func (e *MachineSetFailedError) Error() string {
+	failures := []string{}
+	for _, f := range e.Failures {
+		failures = append(failures, f.String())
+	}
+	return fmt.Sprintf("MachineSet %v has failed machines: %v", e.MachineSet, strings.Join(failures, "; "))
+}
+
+// MachineSetState is the state of a MachineSet and of its Machines.
+type MachineSetState struct {
+	MachineSet *machinev1beta1.MachineSet
+	Machines   []machinev1beta1.Machine
+	// Ready when all the desired replicas are ready, and no other Machine
+	// remains, e.g. while scaling down
+	Ready bool
+}
+
+// MachineFailures returns the Machines in the Failed phase or with an error reason or message.
+func MachineFailures(machines []machinev1beta1.Machine) []MachineFailure {
+	failures := []MachineFailure{}
+	for _, m := range machines {
+		f := MachineFailure{Machine: m.Name}
+		if m.Status.Phase != nil {
+			f.Phase = *m.Status.Phase
+		}
+		if m.Status.ErrorReason != nil {
+			f.Reason = string(*m.Status.ErrorReason)
+		}
+		if m.Status.ErrorMessage != nil {
+			f.Message = *m.Status.ErrorMessage
+		}
+		if f.Phase == machinev1beta1.PhaseFailed || len(f.Reason) > 0 || len(f.Message) > 0 {
+			failures = append(failures, f)
+		}
+	}
+	return failures
+}
+
+// ReconcileMachineSetReplicas patches the MachineSet replicas when they are
+// not replicas, it returns the MachineSet unchanged otherwise.
+func ReconcileMachineSetReplicas(config *rest.Config, ms *machinev1beta1.MachineSet, replicas int32) (*machinev1beta1.MachineSet, error) {
+	if ms.Spec.Replicas != nil && *ms.Spec.Replicas == replicas {
+		return ms, nil
+	}
+	patch := fmt.Sprintf("{\"spec\": {\"replicas\": %v}}", replicas)
+	return PatchMachineSet(config, ms, []byte(patch), types.MergePatchType)
+}
+
+// GetMachineSetState returns the MachineSet with its Machines. The error is a
+// *MachineSetFailedError when Machines failed, the state is returned with it.
+func GetMachineSetState(config *rest.Config, namespace string, name string) (*MachineSetState, error) {
+	ms, err := GetMachineSet(config, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	machines, err := GetMachinesByLabel(config, namespace, fmt.Sprintf("%v=%v", MachineSetLabel, name))
+	if err != nil {
+		return nil, err
+	}
+	var replicas int32 = 1
+	if ms.Spec.Replicas != nil {
+		replicas = *ms.Spec.Replicas
+	}
+	state := &MachineSetState{
+		MachineSet: ms,
+		Machines:   machines.Items,
+		Ready: ms.Status.ObservedGeneration == ms.Generation &&
+			ms.Status.ReadyReplicas == replicas && int32(len(machines.Items)) == replicas,
+	}
+	if failures := MachineFailures(machines.Items); len(failures) > 0 {
+		return state, &MachineSetFailedError{MachineSet: name, Failures: failures}
+	}
+	return state, nil
+}
@@ -0,0 +1,40 @@
+package ocputils
+
+import (
+	"strings"
+	"testing"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMachineFailures(t *testing.T) {
+	running := machinev1beta1.PhaseRunning
+	provisioning := machinev1beta1.PhaseProvisioning
+	failed := machinev1beta1.PhaseFailed
+	reason := machinev1beta1.InsufficientResourcesMachineError
+	message := "error launching instance: InsufficientInstanceCapacity: We currently do not have sufficient g4dn.xlarge capacity"
+	machines := []machinev1beta1.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Name: "running"}, Status: machinev1beta1.MachineStatus{Phase: &running}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "provisioning"}, Status: machinev1beta1.MachineStatus{Phase: &provisioning}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "no-phase"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "no-capacity"}, Status: machinev1beta1.MachineStatus{Phase: &failed, ErrorReason: &reason, ErrorMessage: &message}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "error-message"}, Status: machinev1beta1.MachineStatus{Phase: &provisioning, ErrorMessage: &message}},
+	}
+	failures := MachineFailures(machines)
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %v", failures)
+	}
+	expected := MachineFailure{Machine: "no-capacity", Phase: failed, Reason: string(reason), Message: message}
+	if failures[0] != expected {
+		t.Errorf("expected %v, got %v", expected, failures[0])
+	}
+	if failures[1].Machine != "error-message" {
+		t.Errorf("expected machine error-message, got %v", failures[1].Machine)
+	}
+
+	err := &MachineSetFailedError{MachineSet: "gpu", Failures: failures}
+	if !strings.Contains(err.Error(), "InsufficientInstanceCapacity") || !strings.Contains(err.Error(), "no-capacity") {
+		t.Errorf("error does not describe the failed machine: %v", err)
+	}
+}
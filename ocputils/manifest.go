@@ -0,0 +1,412 @@
+package ocputils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultFieldManager owns the fields applied by ApplyManifests.
+const DefaultFieldManager = "ci-tools-nvidia-gpu-operator"
+
+// crdEstablishedTimeout is how long the custom resources wait for their CRD
+// applied by the same manifests to be served.
+const crdEstablishedTimeout = time.Minute
+
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// ApplyAction is what ApplyManifests did to an object.
+type ApplyAction string
+
+const (
+	ApplyActionApplied ApplyAction = "applied"
+	ApplyActionKept    ApplyAction = "kept"
+	ApplyActionPruned  ApplyAction = "pruned"
+	ApplyActionFailed  ApplyAction = "failed"
+)
+
+// ApplyOptions configure ApplyManifests, the zero value applies the manifests
+// as they are with DefaultFieldManager.
+type ApplyOptions struct {
+	// FieldManager owns the applied fields, DefaultFieldManager when empty
+	FieldManager string
+	// Force takes the ownership of the fields conflicting with other managers.
+	// The CRDs are never forced, their conflicts are returned.
+	Force bool
+	// KeepCRDs leaves the existing CRDs as they are, e.g. those of another
+	// install, and applies the missing ones only.
+	KeepCRDs bool
+	// Namespace, when set, replaces the namespace of the namespaced objects
+	Namespace string
+	// PruneLabels are set on all the objects. When not empty, the objects
+	// matching them of the applied kinds and namespaces, but missing from the
+	// manifests, are deleted.
+	PruneLabels map[string]string
+}
+
+// ApplyResult is the outcome of ApplyManifests for a single object.
+type ApplyResult struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Action           ApplyAction
+	// Object is the object returned by the server when applied
+	Object *unstructured.Unstructured
+	Err    error
+}
+
+func (r ApplyResult) String() string {
+	name := r.Name
+	if len(r.Namespace) > 0 {
+		name = r.Namespace + "/" + r.Name
+	}
+	if r.Err != nil {
+		return fmt.Sprintf("%v %v %v: %v", r.GroupVersionKind.Kind, name, r.Action, r.Err)
+	}
+	return fmt.Sprintf("%v %v %v", r.GroupVersionKind.Kind, name, r.Action)
+}
+
+// DecodeManifests returns the objects of multi-document YAML manifests, the
+// empty documents are skipped.
+func DecodeManifests(data []byte) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("invalid manifest %d: %v", len(objs)+1, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if len(obj.GetKind()) == 0 || len(obj.GetAPIVersion()) == 0 {
+			return nil, fmt.Errorf("manifest %d has no kind or apiVersion", len(objs)+1)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// ReadManifests returns the content of the files of fsys matching the
+// patterns, in the order of the patterns then of the file names. os.DirFS
+// reads them from disk, an embed.FS from the binary.
+func ReadManifests(fsys fs.FS, patterns ...string) ([][]byte, error) {
+	manifests := [][]byte{}
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no manifest matches %v", pattern)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			data, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, data)
+		}
+	}
+	return manifests, nil
+}
+
+// newRESTMapper maps the kinds of the discovered resources, the
+// subresources are skipped.
+func newRESTMapper(resourceLists []*metav1.APIResourceList) meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, list := range resourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			scope := meta.RESTScopeRoot
+			if r.Namespaced {
+				scope = meta.RESTScopeNamespace
+			}
+			singular := r.SingularName
+			if len(singular) == 0 {
+				singular = strings.ToLower(r.Kind)
+			}
+			mapper.AddSpecific(gv.WithKind(r.Kind), gv.WithResource(r.Name), gv.WithResource(singular), scope)
+		}
+	}
+	return mapper
+}
+
+// discoverRESTMapper returns the RESTMapper of the resources served by the
+// cluster, the groups failing discovery, e.g. unavailable aggregated APIs, are
+// left out.
+func discoverRESTMapper(dc discovery.DiscoveryInterface) (meta.RESTMapper, error) {
+	_, resourceLists, err := dc.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+	return newRESTMapper(resourceLists), nil
+}
+
+// crdGroupKind returns the kind defined by a CRD, and whether obj is a CRD.
+func crdGroupKind(obj *unstructured.Unstructured) (schema.GroupKind, bool) {
+	if obj.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: crdResource.Group, Kind: "CustomResourceDefinition"}) {
+		return schema.GroupKind{}, false
+	}
+	group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+	return schema.GroupKind{Group: group, Kind: kind}, true
+}
+
+// crdEstablished returns whether the Established condition of a CRD is
+// true, its kind is then served.
+func crdEstablished(crd *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Established" && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestApplier applies objects, the mapper is refreshed once per missing
+// kind for the CRDs applied by the same manifests.
+type manifestApplier struct {
+	dc      discovery.DiscoveryInterface
+	dClient dynamic.Interface
+	mapper  meta.RESTMapper
+	opts    ApplyOptions
+	// crds are the applied CRDs by the kind they define, until established
+	crds map[schema.GroupKind]string
+}
+
+// waitEstablished waits for the applied CRD of gk, if any, to be established.
+func (a *manifestApplier) waitEstablished(gk schema.GroupKind) error {
+	name, ok := a.crds[gk]
+	if !ok {
+		return nil
+	}
+	var lastErr error
+	err := wait.PollUntilContextTimeout(context.TODO(), time.Second, crdEstablishedTimeout, true, func(ctx context.Context) (bool, error) {
+		crd, err := a.dClient.Resource(crdResource).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		return crdEstablished(crd), nil
+	})
+	if err != nil && lastErr != nil {
+		err = lastErr
+	}
+	if err != nil {
+		return fmt.Errorf("CustomResourceDefinition %v is not established: %v", name, err)
+	}
+	delete(a.crds, gk)
+	return nil
+}
+
+func (a *manifestApplier) mapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if !meta.IsNoMatchError(err) {
+		return mapping, err
+	}
+	mapper, err := discoverRESTMapper(a.dc)
+	if err != nil {
+		return nil, err
+	}
+	a.mapper = mapper
+	return a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+func (a *manifestApplier) resource(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return a.dClient.Resource(mapping.Resource).Namespace(namespace)
+	}
+	return a.dClient.Resource(mapping.Resource)
+}
+
+func (a *manifestApplier) apply(obj *unstructured.Unstructured) (ApplyResult, *meta.RESTMapping) {
+	obj = obj.DeepCopy()
+	result := ApplyResult{GroupVersionKind: obj.GroupVersionKind(), Name: obj.GetName(), Action: ApplyActionFailed}
+	if err := a.waitEstablished(result.GroupVersionKind.GroupKind()); err != nil {
+		result.Err = err
+		return result, nil
+	}
+	mapping, err := a.mapping(result.GroupVersionKind)
+	if err != nil {
+		result.Err = err
+		return result, nil
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if len(a.opts.Namespace) > 0 {
+			obj.SetNamespace(a.opts.Namespace)
+		}
+		if len(obj.GetNamespace()) == 0 {
+			obj.SetNamespace(metav1.NamespaceDefault)
+		}
+		result.Namespace = obj.GetNamespace()
+	}
+	if len(a.opts.PruneLabels) > 0 {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = map[string]string{}
+		}
+		for k, v := range a.opts.PruneLabels {
+			objLabels[k] = v
+		}
+		obj.SetLabels(objLabels)
+	}
+	fieldManager := a.opts.FieldManager
+	if len(fieldManager) == 0 {
+		fieldManager = DefaultFieldManager
+	}
+	_, isCrd := crdGroupKind(obj)
+	if isCrd && a.opts.KeepCRDs {
+		existing, err := a.resource(mapping, "").Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		if err == nil {
+			result.Action = ApplyActionKept
+			result.Object = existing
+			if gk, _ := crdGroupKind(existing); !crdEstablished(existing) {
+				a.crds[gk] = existing.GetName()
+			}
+			return result, mapping
+		}
+		if !errors.IsNotFound(err) {
+			result.Err = err
+			return result, mapping
+		}
+	}
+	force := a.opts.Force && !isCrd
+	applied, err := a.resource(mapping, result.Namespace).Apply(context.TODO(), obj.GetName(), obj, metav1.ApplyOptions{FieldManager: fieldManager, Force: force})
+	if err != nil {
+		result.Err = err
+		return result, mapping
+	}
+	result.Action = ApplyActionApplied
+	result.Object = applied
+	if gk, ok := crdGroupKind(applied); ok && !crdEstablished(applied) {
+		a.crds[gk] = applied.GetName()
+	}
+	return result, mapping
+}
+
+// prune deletes the objects labeled with the prune labels of the applied
+// resources and namespaces, missing from the applied results.
+func (a *manifestApplier) prune(mappings map[schema.GroupVersionResource]*meta.RESTMapping, namespaces map[schema.GroupVersionResource]map[string]bool, applied map[string]bool) []ApplyResult {
+	results := []ApplyResult{}
+	selector := labels.SelectorFromSet(a.opts.PruneLabels).String()
+	for gvr, mapping := range mappings {
+		for namespace := range namespaces[gvr] {
+			list, err := a.resource(mapping, namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				results = append(results, ApplyResult{GroupVersionKind: mapping.GroupVersionKind, Namespace: namespace, Action: ApplyActionFailed, Err: err})
+				continue
+			}
+			for _, obj := range list.Items {
+				if applied[objectKey(mapping.GroupVersionKind.GroupKind(), obj.GetNamespace(), obj.GetName())] {
+					continue
+				}
+				result := ApplyResult{GroupVersionKind: mapping.GroupVersionKind, Namespace: obj.GetNamespace(), Name: obj.GetName(), Action: ApplyActionPruned}
+				err := a.resource(mapping, obj.GetNamespace()).Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{})
+				if err != nil && !errors.IsNotFound(err) {
+					result.Action = ApplyActionFailed
+					result.Err = err
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+func objectKey(gk schema.GroupKind, namespace string, name string) string {
+	return fmt.Sprintf("%v/%v/%v", gk, namespace, name)
+}
+
+// ApplyObjects applies the objects in order with server-side apply, the CRDs
+// must come before their custom resources, which wait for the CRDs to be
+// established. It returns a result per object,
+// and per pruned object, with the errors aggregated.
+func ApplyObjects(config *rest.Config, opts ApplyOptions, objs []*unstructured.Unstructured) ([]ApplyResult, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := discoverRESTMapper(dc)
+	if err != nil {
+		return nil, err
+	}
+	a := &manifestApplier{dc: dc, dClient: dClient, mapper: mapper, opts: opts, crds: map[schema.GroupKind]string{}}
+
+	results := []ApplyResult{}
+	mappings := map[schema.GroupVersionResource]*meta.RESTMapping{}
+	namespaces := map[schema.GroupVersionResource]map[string]bool{}
+	applied := map[string]bool{}
+	for _, obj := range objs {
+		result, mapping := a.apply(obj)
+		results = append(results, result)
+		if mapping == nil {
+			continue
+		}
+		if mappings[mapping.Resource] == nil {
+			mappings[mapping.Resource] = mapping
+			namespaces[mapping.Resource] = map[string]bool{}
+		}
+		namespaces[mapping.Resource][result.Namespace] = true
+		applied[objectKey(result.GroupVersionKind.GroupKind(), result.Namespace, result.Name)] = true
+	}
+	if len(opts.PruneLabels) > 0 {
+		results = append(results, a.prune(mappings, namespaces, applied)...)
+	}
+
+	errs := []error{}
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%v", result))
+		}
+	}
+	return results, utilerrors.NewAggregate(errs)
+}
+
+// ApplyManifests applies the objects of multi-document YAML manifests, read
+// with ReadManifests or embedded, see ApplyObjects.
+func ApplyManifests(config *rest.Config, opts ApplyOptions, manifests ...[]byte) ([]ApplyResult, error) {
+	objs := []*unstructured.Unstructured{}
+	for _, data := range manifests {
+		decoded, err := DecodeManifests(data)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, decoded...)
+	}
+	return ApplyObjects(config, opts, objs)
+}
@@ -0,0 +1,142 @@
+package ocputils
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	data := []byte(`# comment only
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+---
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: ds
+  namespace: test
+`)
+	objs, err := DecodeManifests(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objs))
+	}
+	if objs[0].GetKind() != "Namespace" || objs[1].GetKind() != "DaemonSet" || objs[1].GetNamespace() != "test" {
+		t.Errorf("unexpected objects %v/%v, %v/%v", objs[0].GetKind(), objs[0].GetName(), objs[1].GetKind(), objs[1].GetName())
+	}
+
+	if _, err := DecodeManifests([]byte("metadata:\n  name: test\n")); err == nil {
+		t.Error("expected an error for a manifest without kind")
+	}
+}
+
+func TestReadManifests(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifests/b.yaml": {Data: []byte("kind: B")},
+		"manifests/a.yaml": {Data: []byte("kind: A")},
+		"crds/crd.yaml":    {Data: []byte("kind: CRD")},
+	}
+	manifests, err := ReadManifests(fsys, "crds/*.yaml", "manifests/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, m := range manifests {
+		got = append(got, string(m))
+	}
+	if expected := []string{"kind: CRD", "kind: A", "kind: B"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := ReadManifests(fsys, "missing/*.yaml"); err == nil {
+		t.Error("expected an error for a pattern without files")
+	}
+}
+
+func TestNewRESTMapper(t *testing.T) {
+	mapper := newRESTMapper([]*metav1.APIResourceList{
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "daemonsets", SingularName: "daemonset", Namespaced: true, Kind: "DaemonSet"},
+				{Name: "daemonsets/status", Namespaced: true, Kind: "DaemonSet"},
+			},
+		},
+		{
+			GroupVersion: "nfd.k8s-sigs.io/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "nodefeaturerules", Kind: "NodeFeatureRule"},
+			},
+		},
+	})
+
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: "apps", Kind: "DaemonSet"}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Resource.Resource != "daemonsets" || mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		t.Errorf("unexpected DaemonSet mapping %v %v", mapping.Resource, mapping.Scope.Name())
+	}
+	mapping, err = mapper.RESTMapping(schema.GroupKind{Group: "nfd.k8s-sigs.io", Kind: "NodeFeatureRule"}, "v1alpha1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Resource.Resource != "nodefeaturerules" || mapping.Scope.Name() != meta.RESTScopeNameRoot {
+		t.Errorf("unexpected NodeFeatureRule mapping %v %v", mapping.Resource, mapping.Scope.Name())
+	}
+	if _, err := mapper.RESTMapping(schema.GroupKind{Group: "nfd.openshift.io", Kind: "NodeFeatureRule"}, "v1alpha1"); !meta.IsNoMatchError(err) {
+		t.Errorf("expected a no match error, got %v", err)
+	}
+}
+
+func TestCrdEstablished(t *testing.T) {
+	objs, err := DecodeManifests([]byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: nodefeaturerules.nfd.k8s-sigs.io
+spec:
+  group: nfd.k8s-sigs.io
+  names:
+    kind: NodeFeatureRule
+status:
+  conditions:
+  - type: NamesAccepted
+    status: "True"
+  - type: Established
+    status: "False"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crd := objs[0]
+	gk, ok := crdGroupKind(crd)
+	if !ok || gk != (schema.GroupKind{Group: "nfd.k8s-sigs.io", Kind: "NodeFeatureRule"}) {
+		t.Errorf("unexpected CRD kind %v %v", gk, ok)
+	}
+	if crdEstablished(crd) {
+		t.Errorf("expected a CRD not established")
+	}
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	conditions[1].(map[string]interface{})["status"] = "True"
+	if err := unstructured.SetNestedSlice(crd.Object, conditions, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	if !crdEstablished(crd) {
+		t.Errorf("expected an established CRD")
+	}
+	if _, ok := crdGroupKind(&unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}}); ok {
+		t.Errorf("a ConfigMap is not a CRD")
+	}
+}
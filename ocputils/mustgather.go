@@ -0,0 +1,247 @@
+package ocputils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	nfdv1 "github.com/openshift/cluster-nfd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DriverPodLabel selects the driver pods of the GPU operator. The app
+	// label carries the RHCOS version on OpenShift, the component label does not.
+	DriverPodLabel = "app.kubernetes.io/component=nvidia-driver"
+	// DriverContainerName is the container of the driver pods running nvidia-smi
+	DriverContainerName = "nvidia-driver-ctr"
+)
+
+const (
+	mustGatherRoot = "must-gather"
+	// nvidia-bug-report.sh writes the report to the working directory
+	bugReportCommand = "cd /tmp && nvidia-bug-report.sh >&2 && cat nvidia-bug-report.log.gz"
+)
+
+// MustGatherOptions selects what MustGather collects.
+type MustGatherOptions struct {
+	// Namespace of the GPU operator and its operands.
+	Namespace string
+	// DestDir is the directory the tarball is written to.
+	DestDir string
+	// BugReport runs nvidia-bug-report.sh in every driver pod.
+	BugReport bool
+}
+
+// MustGatherResult lists the files written to the tarball and the data that
+// could not be collected.
+type MustGatherResult struct {
+	Tarball string   `json:"tarball"`
+	Files   []string `json:"files"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// mustGather collects into a tarball and records the errors, so that a
+// missing resource does not prevent collecting the others.
+type mustGather struct {
+	config *rest.Config
+	tw     *tar.Writer
+	result *MustGatherResult
+}
+
+// MustGather collects the data of the NVIDIA GPU operator must-gather script
+// with the API, whatever the install method, into
+// <DestDir>/gpu-operator-must-gather-<timestamp>.tar.gz.
+func MustGather(config *rest.Config, opts MustGatherOptions) (*MustGatherResult, error) {
+	if err := os.MkdirAll(opts.DestDir, 0755); err != nil {
+		return nil, err
+	}
+	tarball := path.Join(opts.DestDir, fmt.Sprintf("gpu-operator-must-gather-%v.tar.gz", time.Now().Format("20060102-150405")))
+	f, err := os.Create(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	mg := &mustGather{
+		config: config,
+		tw:     tar.NewWriter(gz),
+		result: &MustGatherResult{Tarball: tarball},
+	}
+	mg.gatherCluster()
+	mg.gatherNamespace(opts.Namespace)
+	mg.gatherGpuNodes()
+	if opts.BugReport {
+		mg.gatherBugReports(opts.Namespace)
+	}
+	if len(mg.result.Errors) > 0 {
+		mg.add("errors.txt", []byte(strings.Join(mg.result.Errors, "\n")+"\n"))
+	}
+	if err := mg.tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return mg.result, nil
+}
+
+func (mg *mustGather) check(err error, what string) bool {
+	if err == nil {
+		return true
+	}
+	mg.result.Errors = append(mg.result.Errors, fmt.Sprintf("%v: %v", what, err))
+	return false
+}
+
+func (mg *mustGather) add(name string, data []byte) {
+	name = path.Join(mustGatherRoot, name)
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if !mg.check(mg.tw.WriteHeader(header), name) {
+		return
+	}
+	_, err := mg.tw.Write(data)
+	if mg.check(err, name) {
+		mg.result.Files = append(mg.result.Files, name)
+	}
+}
+
+func (mg *mustGather) addYaml(name string, obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	if mg.check(err, name) {
+		mg.add(name, data)
+	}
+}
+
+func (mg *mustGather) gatherCluster() {
+	version, err := GetServerVersion(mg.config)
+	if mg.check(err, "cluster version") {
+		mg.addYaml("version.yaml", version)
+	}
+	clusterPolicies, err := ListDynamicResource(mg.config, gpuv1.GroupVersion.WithResource("clusterpolicies"))
+	if mg.check(err, "clusterpolicies") {
+		mg.addYaml("cluster_policy.yaml", clusterPolicies)
+	}
+	nfds, err := ListDynamicResource(mg.config, nfdv1.GroupVersion.WithResource("nodefeaturediscoveries"))
+	if mg.check(err, "nodefeaturediscoveries") {
+		mg.addYaml("nvidia_nfd.yaml", nfds)
+	}
+}
+
+// gatherNamespace collects the OLM objects, the DaemonSets, the events and
+// the pods of the namespace with the logs of all of their containers.
+func (mg *mustGather) gatherNamespace(namespace string) {
+	csvs, err := GetCsvsByLabel(mg.config, namespace, "")
+	if mg.check(err, "csvs") {
+		mg.addYaml(path.Join(namespace, "csvs.yaml"), csvs)
+	}
+	subs, err := GetSubscriptions(mg.config, namespace)
+	if mg.check(err, "subscriptions") {
+		mg.addYaml(path.Join(namespace, "subscriptions.yaml"), subs)
+	}
+	installPlans, err := GetInstallPlans(mg.config, namespace)
+	if mg.check(err, "installplans") {
+		mg.addYaml(path.Join(namespace, "installplans.yaml"), installPlans)
+	}
+	daemonSets, err := GetDaemonsets(mg.config, namespace)
+	if mg.check(err, "daemonsets") {
+		mg.addYaml(path.Join(namespace, "daemonsets.yaml"), daemonSets)
+	}
+	events, err := GetEventsByFieldSelector(mg.config, namespace, "")
+	if mg.check(err, "events") {
+		mg.addYaml(path.Join(namespace, "events.yaml"), events)
+	}
+	pods, err := GetPodsByLabel(mg.config, namespace, "")
+	if !mg.check(err, "pods") {
+		return
+	}
+	mg.addYaml(path.Join(namespace, "pods.yaml"), pods)
+	for _, pod := range pods.Items {
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			mg.gatherLogs(pod, status.Name, false)
+			if status.RestartCount > 0 {
+				mg.gatherLogs(pod, status.Name, true)
+			}
+		}
+	}
+}
+
+func (mg *mustGather) gatherLogs(pod corev1.Pod, container string, previous bool) {
+	name := fmt.Sprintf("%v_%v.log", pod.Name, container)
+	if previous {
+		name = fmt.Sprintf("%v_%v_previous.log", pod.Name, container)
+	}
+	logs, err := GetContainerLogs(mg.config, pod, container, previous)
+	if mg.check(err, fmt.Sprintf("logs of %v/%v", pod.Name, container)) {
+		mg.add(path.Join(pod.Namespace, "logs", name), []byte(*logs))
+	}
+}
+
+type gpuNodeSummary struct {
+	Name        string              `json:"name"`
+	Labels      map[string]string   `json:"labels"`
+	Capacity    corev1.ResourceList `json:"capacity"`
+	Allocatable corev1.ResourceList `json:"allocatable"`
+}
+
+func (mg *mustGather) gatherGpuNodes() {
+	nodes, err := GetNodesByLabel(mg.config, "nvidia.com/gpu.present=true")
+	if !mg.check(err, "gpu nodes") {
+		return
+	}
+	summary := []gpuNodeSummary{}
+	for _, node := range nodes.Items {
+		mg.addYaml(path.Join("nodes", fmt.Sprintf("%v.yaml", node.Name)), node)
+		summary = append(summary, gpuNodeSummary{
+			Name:        node.Name,
+			Labels:      node.Labels,
+			Capacity:    node.Status.Capacity,
+			Allocatable: node.Status.Allocatable,
+		})
+	}
+	mg.addYaml("gpu_nodes.yaml", summary)
+}
+
+// gatherBugReports runs nvidia-bug-report.sh in the driver pod of every GPU
+// node and records the nodes without a running driver pod.
+func (mg *mustGather) gatherBugReports(namespace string) {
+	nodes, err := GetNodesByLabel(mg.config, "nvidia.com/gpu.present=true")
+	if !mg.check(err, "gpu nodes") {
+		return
+	}
+	pods, err := GetPodsByLabel(mg.config, namespace, DriverPodLabel)
+	if !mg.check(err, "driver pods") {
+		return
+	}
+	driverPods := map[string]corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			driverPods[pod.Spec.NodeName] = pod
+		}
+	}
+	for _, node := range nodes.Items {
+		pod, ok := driverPods[node.Name]
+		if !ok {
+			mg.check(fmt.Errorf("no running driver pod with label %v", DriverPodLabel), fmt.Sprintf("nvidia-bug-report of node %v", node.Name))
+			continue
+		}
+		report, _, err := ExecInPod(mg.config, pod, DriverContainerName, []string{"bash", "-c", bugReportCommand})
+		if mg.check(err, fmt.Sprintf("nvidia-bug-report of %v", pod.Name)) {
+			mg.add(path.Join("nvidia-bug-report", fmt.Sprintf("nvidia-bug-report_%v.log.gz", node.Name)), report)
+		}
+	}
+}
@@ -1,43 +1,41 @@
 package ocputils
 
 import (
-	"context"
 	"fmt"
 
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
-	machinev1beta1client "github.com/openshift/client-go/machine/clientset/versioned/typed/machine/v1beta1"
 	corev1 "k8s.io/api/core/v1"
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
-func GetNodesByLabelSelector(config *rest.Config, labelSelector string) (*corev1.NodeList, error) {
-	clientset, err := kubernetes.NewForConfig(config)
+const (
+	// CiMachineSetLabel marks the test GPU MachineSets, created by
+	// scale_gpu_nodes or test_gpu_autoscaling, the only ones deleted by
+	// delete_gpu_nodes.
+	CiMachineSetLabel      = "ci-tools-nvidia-gpu-operator/created-by"
+	CiMachineSetLabelValue = "scale_gpu_nodes"
+	// MachineSetLabel selects the machines of a MachineSet
+	MachineSetLabel = "machine.openshift.io/cluster-api-machineset"
+)
+
+func GetNodesByLabel(config *rest.Config, labelSelector string) (*corev1.NodeList, error) {
+	items, err := List[corev1.Node](config, "", labelSelector)
 	if err != nil {
 		return nil, err
 	}
-	return clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
+	return &corev1.NodeList{Items: items}, nil
 }
-func GetNodesByLabel(config *rest.Config, labelselector string) (*v1.NodeList, error) {
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
-		LabelSelector: labelselector,
-	})
+
+func GetNode(config *rest.Config, name string) (*corev1.Node, error) {
+	return Get[corev1.Node](config, "", name)
 }
 
-func GetNodesByRole(config *rest.Config, role string) (*v1.NodeList, error) {
+func GetNodesByRole(config *rest.Config, role string) (*corev1.NodeList, error) {
 	return GetNodesByLabel(config, fmt.Sprintf("node-role.kubernetes.io/%v", role))
 }
 
-func GetFirstWorkerNode(config *rest.Config) (*v1.Node, error) {
+func GetFirstWorkerNode(config *rest.Config) (*corev1.Node, error) {
 	nodes, err := GetNodesByRole(config, "worker")
 	if err != nil {
 		return nil, err
@@ -46,18 +44,14 @@ func GetFirstWorkerNode(config *rest.Config) (*v1.Node, error) {
 }
 
 func GetWorkerMachineSets(config *rest.Config, namespace string) (*machinev1beta1.MachineSetList, error) {
-	clientset, err := machinev1beta1client.NewForConfig(config)
+	machineSets, err := List[machinev1beta1.MachineSet](config, namespace, "")
 	if err != nil {
 		return nil, err
 	}
 	list := &machinev1beta1.MachineSetList{
 		Items: []machinev1beta1.MachineSet{},
 	}
-	resp, err := clientset.MachineSets(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
-	for _, ms := range resp.Items {
+	for _, ms := range machineSets {
 		if val, ok := ms.Spec.Template.ObjectMeta.Labels["machine.openshift.io/cluster-api-machine-role"]; ok && val == "worker" {
 			list.Items = append(list.Items, ms)
 		}
@@ -66,25 +60,42 @@ func GetWorkerMachineSets(config *rest.Config, namespace string) (*machinev1beta
 }
 
 func PatchMachineSet(config *rest.Config, ms *machinev1beta1.MachineSet, data []byte, pt types.PatchType) (*machinev1beta1.MachineSet, error) {
-	clientset, err := machinev1beta1client.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return clientset.MachineSets(ms.Namespace).Patch(context.TODO(), ms.Name, pt, data, metav1.PatchOptions{})
+	return Patch[machinev1beta1.MachineSet](config, ms.Namespace, ms.Name, data, pt)
 }
 
 func GetMachineSet(config *rest.Config, namespace string, name string) (*machinev1beta1.MachineSet, error) {
-	clientset, err := machinev1beta1client.NewForConfig(config)
+	return Get[machinev1beta1.MachineSet](config, namespace, name)
+}
+
+func CreateMachineSet(config *rest.Config, namespace string, ms *machinev1beta1.MachineSet) (*machinev1beta1.MachineSet, error) {
+	ms = ms.DeepCopy()
+	ms.Namespace = namespace
+	return Create(config, ms)
+}
+
+func GetMachineSetsByLabel(config *rest.Config, namespace string, labelSelector string) (*machinev1beta1.MachineSetList, error) {
+	items, err := List[machinev1beta1.MachineSet](config, namespace, labelSelector)
 	if err != nil {
 		return nil, err
 	}
-	return clientset.MachineSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	return &machinev1beta1.MachineSetList{Items: items}, nil
 }
 
-func CreateMachineSet(config *rest.Config, namespace string, ms *machinev1beta1.MachineSet) (*machinev1beta1.MachineSet, error) {
-	clientset, err := machinev1beta1client.NewForConfig(config)
+func DeleteMachineSet(config *rest.Config, namespace string, name string) error {
+	ms := &machinev1beta1.MachineSet{}
+	ms.Namespace = namespace
+	ms.Name = name
+	return Delete(config, ms)
+}
+
+func GetMachinesByLabel(config *rest.Config, namespace string, labelSelector string) (*machinev1beta1.MachineList, error) {
+	items, err := List[machinev1beta1.Machine](config, namespace, labelSelector)
 	if err != nil {
 		return nil, err
 	}
-	return clientset.MachineSets(namespace).Create(context.TODO(), ms, metav1.CreateOptions{})
+	return &machinev1beta1.MachineList{Items: items}, nil
+}
+
+func GetMachine(config *rest.Config, namespace string, name string) (*machinev1beta1.Machine, error) {
+	return Get[machinev1beta1.Machine](config, namespace, name)
 }
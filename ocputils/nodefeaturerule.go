@@ -0,0 +1,116 @@
+package ocputils
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// The NodeFeatureRule CRD moved from the OpenShift NFD group to the upstream one
+var nodeFeatureRuleGroupVersions = []schema.GroupVersion{
+	{Group: "nfd.k8s-sigs.io", Version: "v1alpha1"},
+	{Group: "nfd.openshift.io", Version: "v1alpha1"},
+}
+
+// NodeFeatureRule holds the fields of a cluster scoped NFD NodeFeatureRule.
+type NodeFeatureRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		Rules []NodeFeatureRuleSpecRule `json:"rules"`
+	} `json:"spec"`
+}
+
+// NodeFeatureRuleSpecRule sets Labels on the nodes matching all the MatchFeatures.
+type NodeFeatureRuleSpecRule struct {
+	Name          string               `json:"name"`
+	Labels        map[string]string    `json:"labels,omitempty"`
+	MatchFeatures []FeatureMatcherTerm `json:"matchFeatures,omitempty"`
+}
+
+// FeatureMatcherTerm matches the elements of a feature, e.g. pci.device, by attribute.
+type FeatureMatcherTerm struct {
+	Feature          string                     `json:"feature"`
+	MatchExpressions map[string]MatchExpression `json:"matchExpressions"`
+}
+
+type MatchExpression struct {
+	Op    string   `json:"op"`
+	Value []string `json:"value,omitempty"`
+}
+
+// CreateNodeFeatureRule creates the rule in the first NodeFeatureRule API
+// served by the cluster. It returns a NotFound error when NFD serves none.
+func CreateNodeFeatureRule(config *rest.Config, rule *NodeFeatureRule) (*NodeFeatureRule, error) {
+	var err error
+	for _, gv := range nodeFeatureRuleGroupVersions {
+		rule.SetGroupVersionKind(gv.WithKind("NodeFeatureRule"))
+		var created *NodeFeatureRule
+		created, err = Create(config, rule)
+		if err == nil {
+			return created, nil
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// DeleteNodeFeatureRule deletes the rule from the API it was created in.
+func DeleteNodeFeatureRule(config *rest.Config, rule *NodeFeatureRule) error {
+	return Delete(config, rule)
+}
+
+func (in *NodeFeatureRule) DeepCopyInto(out *NodeFeatureRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec.Rules != nil {
+		out.Spec.Rules = make([]NodeFeatureRuleSpecRule, len(in.Spec.Rules))
+		for i := range in.Spec.Rules {
+			in.Spec.Rules[i].DeepCopyInto(&out.Spec.Rules[i])
+		}
+	}
+}
+
+func (in *NodeFeatureRule) DeepCopy() *NodeFeatureRule {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFeatureRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *NodeFeatureRule) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *NodeFeatureRuleSpecRule) DeepCopyInto(out *NodeFeatureRuleSpecRule) {
+	*out = *in
+	if in.Labels != nil {
+		out.Labels = make(map[string]string, len(in.Labels))
+		for key, val := range in.Labels {
+			out.Labels[key] = val
+		}
+	}
+	if in.MatchFeatures != nil {
+		out.MatchFeatures = make([]FeatureMatcherTerm, len(in.MatchFeatures))
+		for i, term := range in.MatchFeatures {
+			out.MatchFeatures[i].Feature = term.Feature
+			if term.MatchExpressions == nil {
+				continue
+			}
+			out.MatchFeatures[i].MatchExpressions = make(map[string]MatchExpression, len(term.MatchExpressions))
+			for key, expr := range term.MatchExpressions {
+				out.MatchFeatures[i].MatchExpressions[key] = MatchExpression{
+					Op:    expr.Op,
+					Value: append([]string(nil), expr.Value...),
+				}
+			}
+		}
+	}
+}
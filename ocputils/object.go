@@ -0,0 +1,310 @@
+package ocputils
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	nfdv1 "github.com/openshift/cluster-nfd-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// Scheme has the kinds of the objects handled by Get, List, Create, Update,
+// Patch, Delete and Ensure.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(Scheme))
+	utilruntime.Must(configv1.Install(Scheme))
+	utilruntime.Must(machinev1beta1.Install(Scheme))
+	utilruntime.Must(gpuv1.AddToScheme(Scheme))
+	utilruntime.Must(nfdv1.AddToScheme(Scheme))
+	Scheme.AddKnownTypes(clusterAutoscalerGroupVersion, &ClusterAutoscaler{})
+	Scheme.AddKnownTypes(machineAutoscalerGroupVersion, &MachineAutoscaler{})
+	for _, gv := range nodeFeatureRuleGroupVersions {
+		Scheme.AddKnownTypes(gv, &NodeFeatureRule{})
+	}
+}
+
+// Object is an API object with generated deep copy functions.
+type Object interface {
+	metav1.Object
+	runtime.Object
+}
+
+// ObjectPtr is a pointer to an Object of type T, it lets the generic
+// functions allocate the objects they return.
+type ObjectPtr[T any] interface {
+	*T
+	Object
+}
+
+// ObjectError is an API error for an object, errors.IsNotFound and the other
+// API error checks see through it.
+type ObjectError struct {
+	Verb      string
+	Kind      string
+	Namespace string
+	Name      string
+	Err       error
+}
+
+func (e *ObjectError) Error() string {
+	name := e.Name
+	if len(e.Namespace) > 0 {
+		name = e.Namespace + "/" + e.Name
+	}
+	if len(name) == 0 {
+		return fmt.Sprintf("%v %v: %v", e.Verb, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%v %v %v: %v", e.Verb, e.Kind, name, e.Err)
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// objectKind returns the kind of obj registered in Scheme, and its resource.
+// A type registered in several versions uses the apiVersion of obj when set.
+// The resource is guessed from the kind, as kubectl does for the kinds
+// without discovery.
+func objectKind(obj runtime.Object) (schema.GroupVersionKind, schema.GroupVersionResource, error) {
+	gvks, _, err := Scheme.ObjectKinds(obj)
+	if err != nil {
+		return schema.GroupVersionKind{}, schema.GroupVersionResource{}, err
+	}
+	gvk := gvks[0]
+	for _, registered := range gvks {
+		if registered == obj.GetObjectKind().GroupVersionKind() {
+			gvk = registered
+		}
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return gvk, gvr, nil
+}
+
+// objectClient returns the dynamic client of the kind of obj, in namespace
+// unless empty.
+func objectClient(config *rest.Config, obj runtime.Object, namespace string) (dynamic.ResourceInterface, schema.GroupVersionKind, error) {
+	gvk, gvr, err := objectKind(obj)
+	if err != nil {
+		return nil, gvk, err
+	}
+	dClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, gvk, err
+	}
+	if len(namespace) == 0 {
+		return dClient.Resource(gvr), gvk, nil
+	}
+	return dClient.Resource(gvr).Namespace(namespace), gvk, nil
+}
+
+// objectRequest returns the dynamic client of the kind of obj in its
+// namespace, and obj to send.
+func objectRequest(config *rest.Config, obj Object) (dynamic.ResourceInterface, schema.GroupVersionKind, *unstructured.Unstructured, error) {
+	client, gvk, err := objectClient(config, obj, obj.GetNamespace())
+	if err != nil {
+		return nil, gvk, nil, err
+	}
+	u, err := toUnstructured(obj, gvk)
+	if err != nil {
+		return nil, gvk, nil, err
+	}
+	return client, gvk, u, nil
+}
+
+// objectError returns err for the object, its kind is the Go type of obj
+// when the kind is not registered in Scheme.
+func objectError(verb string, obj runtime.Object, gvk schema.GroupVersionKind, namespace string, name string, err error) error {
+	kind := gvk.Kind
+	if len(kind) == 0 && obj != nil {
+		kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	}
+	return &ObjectError{Verb: verb, Kind: kind, Namespace: namespace, Name: name, Err: err}
+}
+
+func toUnstructured(obj runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	// The typed clients return objects without apiVersion and kind
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
+
+func fromUnstructured[T any, PT ObjectPtr[T]](u *unstructured.Unstructured) (PT, error) {
+	var obj PT = new(T)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Get returns the object of type T, e.g. Get[gpuv1.ClusterPolicy](config, "",
+// name). The namespace is empty for the cluster scoped kinds.
+func Get[T any, PT ObjectPtr[T]](config *rest.Config, namespace string, name string) (PT, error) {
+	client, gvk, err := objectClient(config, PT(new(T)), namespace)
+	if err != nil {
+		return nil, objectError("get", PT(new(T)), gvk, namespace, name, err)
+	}
+	resp, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, objectError("get", nil, gvk, namespace, name, err)
+	}
+	obj, err := fromUnstructured[T, PT](resp)
+	if err != nil {
+		return nil, objectError("get", nil, gvk, namespace, name, err)
+	}
+	return obj, nil
+}
+
+// List returns the objects of type T matching labelSelector, in all the
+// namespaces when namespace is empty.
+func List[T any, PT ObjectPtr[T]](config *rest.Config, namespace string, labelSelector string) ([]T, error) {
+	client, gvk, err := objectClient(config, PT(new(T)), namespace)
+	if err != nil {
+		return nil, objectError("list", PT(new(T)), gvk, namespace, "", err)
+	}
+	resp, err := client.List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, objectError("list", nil, gvk, namespace, "", err)
+	}
+	items := make([]T, 0, len(resp.Items))
+	for i := range resp.Items {
+		obj, err := fromUnstructured[T, PT](&resp.Items[i])
+		if err != nil {
+			return nil, objectError("list", nil, gvk, resp.Items[i].GetNamespace(), resp.Items[i].GetName(), err)
+		}
+		items = append(items, *obj)
+	}
+	return items, nil
+}
+
+// Create creates obj in its namespace, and returns the created object.
+func Create[T any, PT ObjectPtr[T]](config *rest.Config, obj PT) (PT, error) {
+	client, gvk, u, err := objectRequest(config, obj)
+	if err != nil {
+		return nil, objectError("create", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	resp, err := client.Create(context.TODO(), u, metav1.CreateOptions{})
+	if err != nil {
+		return nil, objectError("create", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	created, err := fromUnstructured[T, PT](resp)
+	if err != nil {
+		return nil, objectError("create", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return created, nil
+}
+
+// Update replaces obj, its resourceVersion must be the current one.
+func Update[T any, PT ObjectPtr[T]](config *rest.Config, obj PT) (PT, error) {
+	client, gvk, u, err := objectRequest(config, obj)
+	if err != nil {
+		return nil, objectError("update", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	resp, err := client.Update(context.TODO(), u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, objectError("update", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	updated, err := fromUnstructured[T, PT](resp)
+	if err != nil {
+		return nil, objectError("update", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return updated, nil
+}
+
+// Patch patches the object of type T with data, and returns the patched object.
+func Patch[T any, PT ObjectPtr[T]](config *rest.Config, namespace string, name string, data []byte, pt types.PatchType) (PT, error) {
+	client, gvk, err := objectClient(config, PT(new(T)), namespace)
+	if err != nil {
+		return nil, objectError("patch", PT(new(T)), gvk, namespace, name, err)
+	}
+	resp, err := client.Patch(context.TODO(), name, pt, data, metav1.PatchOptions{})
+	if err != nil {
+		return nil, objectError("patch", nil, gvk, namespace, name, err)
+	}
+	patched, err := fromUnstructured[T, PT](resp)
+	if err != nil {
+		return nil, objectError("patch", nil, gvk, namespace, name, err)
+	}
+	return patched, nil
+}
+
+// Delete deletes obj, only its namespace and name are used.
+func Delete(config *rest.Config, obj Object) error {
+	client, gvk, err := objectClient(config, obj, obj.GetNamespace())
+	if err != nil {
+		return objectError("delete", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	err = client.Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{})
+	if err != nil {
+		return objectError("delete", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// Ensure creates obj, or updates the existing object to obj, with server-side
+// apply as DefaultFieldManager. Only the fields set in obj are applied, the
+// fields set by the server or by other managers, e.g. the clusterIP of a
+// Service, are kept. The fields owned by another manager are taken over when
+// force is set, otherwise Ensure returns the conflict, see errors.IsConflict.
+func Ensure[T any, PT ObjectPtr[T]](config *rest.Config, obj PT, force bool) (PT, error) {
+	client, gvk, u, err := objectRequest(config, obj)
+	if err != nil {
+		return nil, objectError("ensure", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	// The metadata and the status are set by the server
+	u.SetResourceVersion("")
+	u.SetManagedFields(nil)
+	unstructured.RemoveNestedField(u.Object, "status")
+	removeUnset(u.Object)
+	resp, err := client.Apply(context.TODO(), obj.GetName(), u, metav1.ApplyOptions{FieldManager: DefaultFieldManager, Force: force})
+	if err != nil {
+		return nil, objectError("ensure", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	ensured, err := fromUnstructured[T, PT](resp)
+	if err != nil {
+		return nil, objectError("ensure", obj, gvk, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return ensured, nil
+}
+
+// removeUnset removes the null fields and the empty objects from content.
+// The typed objects convert the unset timestamps to null and the unset
+// structs without omitempty, e.g. resources, to empty objects, applying them
+// would claim fields the caller did not set.
+func removeUnset(content map[string]interface{}) {
+	for key, value := range content {
+		switch value := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			removeUnset(value)
+			if len(value) == 0 {
+				delete(content, key)
+			}
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					removeUnset(item)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ocputils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	nfdv1 "github.com/openshift/cluster-nfd-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestObjectKind(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		kind     string
+		resource string
+	}{
+		{&corev1.Node{}, "/v1, Kind=Node", "/v1, Resource=nodes"},
+		{&gpuv1.ClusterPolicy{}, "nvidia.com/v1, Kind=ClusterPolicy", "nvidia.com/v1, Resource=clusterpolicies"},
+		{&nfdv1.NodeFeatureDiscovery{}, "nfd.openshift.io/v1, Kind=NodeFeatureDiscovery", "nfd.openshift.io/v1, Resource=nodefeaturediscoveries"},
+		{&ClusterAutoscaler{}, "autoscaling.openshift.io/v1, Kind=ClusterAutoscaler", "autoscaling.openshift.io/v1, Resource=clusterautoscalers"},
+		{&MachineAutoscaler{}, "autoscaling.openshift.io/v1beta1, Kind=MachineAutoscaler", "autoscaling.openshift.io/v1beta1, Resource=machineautoscalers"},
+		// Registered in both NFD groups, the apiVersion selects one
+		{&NodeFeatureRule{TypeMeta: metav1.TypeMeta{APIVersion: "nfd.openshift.io/v1alpha1", Kind: "NodeFeatureRule"}}, "nfd.openshift.io/v1alpha1, Kind=NodeFeatureRule", "nfd.openshift.io/v1alpha1, Resource=nodefeaturerules"},
+	}
+	for _, test := range tests {
+		gvk, gvr, err := objectKind(test.obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gvk.String() != test.kind || gvr.String() != test.resource {
+			t.Errorf("expected %v %v, got %v %v", test.kind, test.resource, gvk, gvr)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/nvidia.com/v1/clusterpolicies/gpu-cluster-policy":
+			fmt.Fprint(w, `{"apiVersion":"nvidia.com/v1","kind":"ClusterPolicy","metadata":{"name":"gpu-cluster-policy"},"status":{"state":"ready"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`)
+		}
+	}))
+	defer server.Close()
+	config := &rest.Config{Host: server.URL}
+
+	cp, err := Get[gpuv1.ClusterPolicy](config, "", "gpu-cluster-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Name != "gpu-cluster-policy" || cp.Status.State != "ready" {
+		t.Errorf("unexpected ClusterPolicy %v, state %v", cp.Name, cp.Status.State)
+	}
+
+	_, err = Get[corev1.Pod](config, "test", "missing")
+	if !errors.IsNotFound(err) {
+		t.Fatalf("expected a NotFound error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "get Pod test/missing: ") {
+		t.Errorf("expected the kind, namespace and name in %q", err)
+	}
+}
+
+type unregistered struct {
+	corev1.Pod
+}
+
+func TestEnsure(t *testing.T) {
+	var applied map[string]interface{}
+	var force string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPatch || r.URL.Path != "/apis/apps/v1/namespaces/test/deployments/app" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"MethodNotAllowed","code":405}`)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/apply-patch+yaml" || r.URL.Query().Get("fieldManager") != DefaultFieldManager {
+			t.Errorf("expected a server-side apply, got %v %v", r.Header.Get("Content-Type"), r.URL.RawQuery)
+		}
+		force = r.URL.Query().Get("force")
+		body, _ := io.ReadAll(r.Body)
+		applied = map[string]interface{}{}
+		if err := json.Unmarshal(body, &applied); err != nil {
+			t.Fatal(err)
+		}
+		if force != "true" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"Conflict","code":409}`)
+			return
+		}
+		fmt.Fprint(w, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"app","namespace":"test","resourceVersion":"2"},"spec":{"replicas":0,"progressDeadlineSeconds":600}}`)
+	}))
+	defer server.Close()
+	config := &rest.Config{Host: server.URL}
+
+	replicas := int32(0)
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "app"
+	deployment.Namespace = "test"
+	deployment.ResourceVersion = "1"
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "app", Image: "app:latest"}}
+
+	_, err := Ensure(config, deployment, false)
+	if !errors.IsConflict(err) {
+		t.Fatalf("expected a conflict without force, got %v", err)
+	}
+	ensured, err := Ensure(config, deployment, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ensured.Spec.ProgressDeadlineSeconds == nil || *ensured.Spec.ProgressDeadlineSeconds != 600 {
+		t.Errorf("expected the progressDeadlineSeconds of the server, got %v", ensured.Spec.ProgressDeadlineSeconds)
+	}
+
+	expected := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "app", "namespace": "test"},
+		"spec": map[string]interface{}{
+			"replicas": float64(0),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "app", "image": "app:latest"},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(applied, expected) {
+		t.Errorf("expected only the fields set to be applied, got %v", applied)
+	}
+
+	_, err = Ensure(config, &unregistered{}, false)
+	objErr, ok := err.(*ObjectError)
+	if !ok || objErr.Kind != "unregistered" || objErr.Verb != "ensure" {
+		t.Errorf("expected an ObjectError of kind unregistered, got %v", err)
+	}
+}
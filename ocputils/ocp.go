@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	configv1 "github.com/openshift/api/config/v1"
 	configv1client "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -84,14 +85,20 @@ func ListDynamicResource(config *rest.Config, resource schema.GroupVersionResour
 	return dClient.Resource(resource).List(context.TODO(), metav1.ListOptions{})
 }
 
-func GetDynamicResource[T runtime.Object](config *rest.Config, resource schema.GroupVersionResource, namespace string, name string, obj T) error {
-	dClient, err := dynamic.NewForConfig(config)
+// GetPlatformType returns the platform of the cluster from the Infrastructure
+// CR, e.g. AWS, Azure, GCP or VSphere.
+func GetPlatformType(config *rest.Config) (configv1.PlatformType, error) {
+	oClient, err := configv1client.NewForConfig(config)
 	if err != nil {
-		return err
+		return "", err
 	}
-	resp, err := dClient.Resource(resource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	infra, err := oClient.Infrastructures().Get(context.TODO(), "cluster", metav1.GetOptions{})
 	if err != nil {
-		return err
+		return "", err
+	}
+	if infra.Status.PlatformStatus != nil && len(infra.Status.PlatformStatus.Type) > 0 {
+		return infra.Status.PlatformStatus.Type, nil
 	}
-	return runtime.DefaultUnstructuredConverter.FromUnstructured(resp.UnstructuredContent(), obj)
+	// Deprecated field, the only one set on clusters installed before 4.2
+	return infra.Status.Platform, nil
 }
@@ -122,3 +122,19 @@ func GetAlmExamples(csv *operatorsv1alpha1.ClusterServiceVersion) (string, error
 	}
 	return "", fmt.Errorf("%s not found in given csv %v", almExamples, csv)
 }
+
+func GetSubscriptions(config *rest.Config, namespace string) (*operatorsv1alpha1.SubscriptionList, error) {
+	opClient, err := operatorsv1alpha1clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return opClient.Subscriptions(namespace).List(context.TODO(), metav1.ListOptions{})
+}
+
+func GetInstallPlans(config *rest.Config, namespace string) (*operatorsv1alpha1.InstallPlanList, error) {
+	opClient, err := operatorsv1alpha1clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return opClient.InstallPlans(namespace).List(context.TODO(), metav1.ListOptions{})
+}
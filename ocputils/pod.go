@@ -9,7 +9,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
 func GetPodsByLabel(config *rest.Config, namespace string, labelSelector string) (*corev1.PodList, error) {
@@ -22,6 +24,14 @@ func GetPodsByLabel(config *rest.Config, namespace string, labelSelector string)
 	})
 }
 
+func CreatePod(config *rest.Config, pod *corev1.Pod) (*corev1.Pod, error) {
+	return Create(config, pod)
+}
+
+func GetPod(config *rest.Config, namespace string, name string) (*corev1.Pod, error) {
+	return Get[corev1.Pod](config, namespace, name)
+}
+
 func GetPodLogs(config *rest.Config, pod corev1.Pod, follow bool) (*string, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -57,3 +67,54 @@ func PodProxyGet(config *rest.Config, pod corev1.Pod, port string, path string,
 	}
 	return resp, nil
 }
+
+// GetContainerLogs returns the logs of a container of the pod, or the logs of
+// its previous instance when previous is set.
+func GetContainerLogs(config *rest.Config, pod corev1.Pod, container string, previous bool) (*string, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	req := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Previous:  previous,
+	})
+	resp, err := req.DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	str := strings.ReplaceAll(string(resp), "\r", "\n")
+	return &str, nil
+}
+
+// ExecInPod runs command in a container of the pod through the pods/exec
+// subresource, and returns its stdout and stderr. The error of a command
+// exiting with a non-zero status is an exec.CodeExitError.
+func ExecInPod(config *rest.Config, pod corev1.Pod, container string, command []string) ([]byte, []byte, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	err = executor.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	return stdout.Bytes(), stderr.Bytes(), err
+}
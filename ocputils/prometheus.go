@@ -0,0 +1,255 @@
+package ocputils
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	MonitoringNamespace      = "openshift-monitoring"
+	thanosQuerierRoute       = "thanos-querier"
+	prometheusServiceAccount = "prometheus-k8s"
+)
+
+var routeResource = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// PrometheusClient queries the Prometheus HTTP API with a bearer token.
+type PrometheusClient struct {
+	URL    string
+	token  string
+	client *http.Client
+}
+
+type PrometheusSample struct {
+	Metric    map[string]string `json:"metric"`
+	Timestamp time.Time         `json:"timestamp"`
+	Value     float64           `json:"value"`
+}
+
+type prometheusResponse struct {
+	Status    string          `json:"status"`
+	ErrorType string          `json:"errorType,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type prometheusVector struct {
+	ResultType string `json:"resultType"`
+	Result     []struct {
+		Metric map[string]string `json:"metric"`
+		Value  []interface{}     `json:"value"`
+	} `json:"result"`
+}
+
+// PrometheusError is returned when the API answered with an error status,
+// e.g. errorType "bad_data" for a query that does not parse.
+type PrometheusError struct {
+	Type    string
+	Message string
+}
+
+func (e *PrometheusError) Error() string {
+	return fmt.Sprintf("prometheus %v error: %v", e.Type, e.Message)
+}
+
+// NewThanosQuerierClient returns a client for the cluster monitoring
+// thanos-querier route, authenticated with a short lived token of the
+// prometheus-k8s ServiceAccount.
+func NewThanosQuerierClient(config *rest.Config) (*PrometheusClient, error) {
+	host, err := GetRouteHost(config, MonitoringNamespace, thanosQuerierRoute)
+	if err != nil {
+		return nil, err
+	}
+	token, err := CreateServiceAccountToken(config, MonitoringNamespace, prometheusServiceAccount, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrometheusClient(fmt.Sprintf("https://%v", host), token), nil
+}
+
+func NewPrometheusClient(baseUrl string, token string) *PrometheusClient {
+	return &PrometheusClient{
+		URL:   baseUrl,
+		token: token,
+		client: &http.Client{
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				// CI clusters serve routes with the self-signed ingress certificate
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+func GetRouteHost(config *rest.Config, namespace string, name string) (string, error) {
+	dClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	route, err := dClient.Resource(routeResource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	host, found, err := unstructured.NestedString(route.Object, "spec", "host")
+	if err != nil {
+		return "", err
+	}
+	if !found || len(host) == 0 {
+		return "", fmt.Errorf("route %v/%v has no host", namespace, name)
+	}
+	return host, nil
+}
+
+func CreateServiceAccountToken(config *rest.Config, namespace string, name string, expiration time.Duration) (string, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	seconds := int64(expiration.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &seconds,
+		},
+	}
+	resp, err := clientset.CoreV1().ServiceAccounts(namespace).CreateToken(context.TODO(), name, tokenRequest, metav1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return resp.Status.Token, nil
+}
+
+// Get calls an API path, e.g. "api/v1/rules", and returns the data field of
+// the response.
+func (p *PrometheusClient) Get(path string, params url.Values) (json.RawMessage, error) {
+	reqUrl := fmt.Sprintf("%v/%v?%v", p.URL, path, params.Encode())
+	req, err := http.NewRequest(http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", p.token))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	promResp := &prometheusResponse{}
+	if err := json.Unmarshal(body, promResp); err != nil {
+		return nil, fmt.Errorf("unexpected response from %v (%v): %v", path, resp.Status, string(body))
+	}
+	if promResp.Status != "success" {
+		return nil, &PrometheusError{Type: promResp.ErrorType, Message: promResp.Error}
+	}
+	return promResp.Data, nil
+}
+
+// Query runs an instant query that returns a vector.
+func (p *PrometheusClient) Query(query string) ([]PrometheusSample, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	data, err := p.Get("api/v1/query", params)
+	if err != nil {
+		return nil, err
+	}
+	vector := &prometheusVector{}
+	if err := json.Unmarshal(data, vector); err != nil {
+		return nil, err
+	}
+	if vector.ResultType != "vector" {
+		return nil, fmt.Errorf("query %q returned a %v, expected a vector", query, vector.ResultType)
+	}
+	samples := []PrometheusSample{}
+	for _, r := range vector.Result {
+		if len(r.Value) != 2 {
+			return nil, fmt.Errorf("unexpected sample value %v", r.Value)
+		}
+		ts, ok := r.Value[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected sample timestamp %v", r.Value[0])
+		}
+		str, ok := r.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected sample value %v", r.Value[1])
+		}
+		val, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, PrometheusSample{
+			Metric:    r.Metric,
+			Timestamp: time.Unix(0, int64(ts*float64(time.Second))),
+			Value:     val,
+		})
+	}
+	return samples, nil
+}
+
+// PrometheusRuleGroup is a rule group of the rules API.
+type PrometheusRuleGroup struct {
+	Name  string                `json:"name"`
+	File  string                `json:"file"`
+	Rules []PrometheusRuleState `json:"rules"`
+}
+
+// PrometheusRuleState is a loaded alerting or recording rule, with its
+// evaluation health and the alerts it currently raises.
+type PrometheusRuleState struct {
+	Name      string            `json:"name"`
+	Query     string            `json:"query"`
+	Type      string            `json:"type"`
+	State     string            `json:"state,omitempty"`
+	Health    string            `json:"health"`
+	LastError string            `json:"lastError,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Alerts    []PrometheusAlert `json:"alerts,omitempty"`
+}
+
+type PrometheusAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	State       string            `json:"state"`
+	ActiveAt    *time.Time        `json:"activeAt,omitempty"`
+	Value       string            `json:"value"`
+}
+
+// Rules returns the rule groups loaded by Prometheus.
+func (p *PrometheusClient) Rules() ([]PrometheusRuleGroup, error) {
+	data, err := p.Get("api/v1/rules", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	rules := &struct {
+		Groups []PrometheusRuleGroup `json:"groups"`
+	}{}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, err
+	}
+	return rules.Groups, nil
+}
+
+// ValidateExpr returns a *PrometheusError of type bad_data when the PromQL
+// expression does not parse. The expression is evaluated, whatever its result.
+func (p *PrometheusClient) ValidateExpr(expr string) error {
+	params := url.Values{}
+	params.Set("query", expr)
+	_, err := p.Get("api/v1/query", params)
+	return err
+}
@@ -0,0 +1,193 @@
+package ocputils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	prometheusConfigSecret  = "prometheus-k8s"
+	prometheusConfigKey     = "prometheus.yaml.gz"
+	serviceMonitorJobPrefix = "serviceMonitor"
+)
+
+// Source labels the Prometheus operator uses to keep the targets of a
+// ServiceMonitor endpoint port, depending on the service discovery role.
+var endpointPortLabels = []string{
+	"__meta_kubernetes_endpoint_port_name",
+	"__meta_kubernetes_endpointslice_port_name",
+}
+
+// Source labels the Prometheus operator uses to keep the targets of a
+// ServiceMonitor endpoint targetPort, by container port name or number.
+const (
+	containerPortNameLabel   = "__meta_kubernetes_pod_container_port_name"
+	containerPortNumberLabel = "__meta_kubernetes_pod_container_port_number"
+)
+
+// PrometheusConfig is the part of the Prometheus configuration generated by
+// the Prometheus operator the tests look at.
+type PrometheusConfig struct {
+	ScrapeConfigs []ScrapeConfig `json:"scrape_configs"`
+}
+
+type ScrapeConfig struct {
+	JobName              string               `json:"job_name"`
+	Scheme               string               `json:"scheme,omitempty"`
+	MetricsPath          string               `json:"metrics_path,omitempty"`
+	KubernetesSDConfigs  []KubernetesSDConfig `json:"kubernetes_sd_configs,omitempty"`
+	RelabelConfigs       []RelabelConfig      `json:"relabel_configs,omitempty"`
+	MetricRelabelConfigs []RelabelConfig      `json:"metric_relabel_configs,omitempty"`
+}
+
+type KubernetesSDConfig struct {
+	Role       string `json:"role"`
+	Namespaces struct {
+		Names []string `json:"names,omitempty"`
+	} `json:"namespaces,omitempty"`
+}
+
+type RelabelConfig struct {
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+	Action       string   `json:"action,omitempty"`
+}
+
+// ServiceMonitorJob is a scrape job generated from a ServiceMonitor endpoint.
+// Its name is serviceMonitor/<namespace>/<name>/<endpoint index>.
+type ServiceMonitorJob struct {
+	Namespace      string
+	ServiceMonitor string
+	Endpoint       int
+	ScrapeConfig
+}
+
+// GetPrometheusConfig returns the parsed Prometheus configuration of the
+// cluster monitoring stack, and the decompressed prometheus.yaml.
+func GetPrometheusConfig(config *rest.Config) (*PrometheusConfig, string, error) {
+	secret, err := GetSecret(config, MonitoringNamespace, prometheusConfigSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	promyml, err := GetSecretValue(secret, prometheusConfigKey, true)
+	if err != nil {
+		return nil, "", err
+	}
+	promConfig, err := ParsePrometheusConfig([]byte(*promyml))
+	if err != nil {
+		return nil, *promyml, err
+	}
+	return promConfig, *promyml, nil
+}
+
+func ParsePrometheusConfig(data []byte) (*PrometheusConfig, error) {
+	promConfig := &PrometheusConfig{}
+	if err := yaml.Unmarshal(data, promConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse prometheus configuration: %v", err)
+	}
+	return promConfig, nil
+}
+
+// ServiceMonitorJobs returns the scrape jobs generated from ServiceMonitors.
+func (c *PrometheusConfig) ServiceMonitorJobs() []ServiceMonitorJob {
+	jobs := []ServiceMonitorJob{}
+	for _, scrapeConfig := range c.ScrapeConfigs {
+		parts := strings.Split(scrapeConfig.JobName, "/")
+		if len(parts) != 4 || parts[0] != serviceMonitorJobPrefix {
+			continue
+		}
+		endpoint, err := strconv.Atoi(parts[3])
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, ServiceMonitorJob{
+			Namespace:      parts[1],
+			ServiceMonitor: parts[2],
+			Endpoint:       endpoint,
+			ScrapeConfig:   scrapeConfig,
+		})
+	}
+	return jobs
+}
+
+// FindServiceMonitorJob returns the job of the ServiceMonitor namespace/name
+// that scrapes the endpoint, and discovers its targets in namespaces, or in
+// any namespace when namespaces is empty.
+func (c *PrometheusConfig) FindServiceMonitorJob(namespace string, name string, endpoint ServiceMonitorEndpoint, namespaces []string) (*ServiceMonitorJob, error) {
+	candidates := []string{}
+	for _, job := range c.ServiceMonitorJobs() {
+		if job.Namespace != namespace || job.ServiceMonitor != name {
+			continue
+		}
+		candidates = append(candidates, job.JobName)
+		if job.KeepsEndpoint(endpoint) && job.DiscoversNamespaces(namespaces) {
+			return &job, nil
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no scrape job for ServiceMonitor %v/%v", namespace, name)
+	}
+	return nil, fmt.Errorf("no scrape job for endpoint %v of ServiceMonitor %v/%v, found %v", endpoint, namespace, name, candidates)
+}
+
+// KeepsEndpoint is true when the job keeps the targets of the endpoint: its
+// named Service port, or else its targetPort container port name or number.
+func (j *ServiceMonitorJob) KeepsEndpoint(endpoint ServiceMonitorEndpoint) bool {
+	switch {
+	case len(endpoint.Port) > 0:
+		return j.keeps(endpointPortLabels, endpoint.Port)
+	case endpoint.TargetPort == nil:
+		return false
+	case endpoint.TargetPort.Type == intstr.String:
+		return j.keeps([]string{containerPortNameLabel}, endpoint.TargetPort.StrVal)
+	default:
+		return j.keeps([]string{containerPortNumberLabel}, endpoint.TargetPort.String())
+	}
+}
+
+func (j *ServiceMonitorJob) keeps(labels []string, regex string) bool {
+	for _, relabel := range j.RelabelConfigs {
+		if relabel.Action != "keep" || len(relabel.SourceLabels) != 1 || relabel.Regex != regex {
+			continue
+		}
+		for _, label := range labels {
+			if relabel.SourceLabels[0] == label {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DiscoversNamespaces is true when the job discovers the targets of all the
+// namespaces, or of any namespace when namespaces is empty.
+func (j *ServiceMonitorJob) DiscoversNamespaces(namespaces []string) bool {
+	for _, sdConfig := range j.KubernetesSDConfigs {
+		if len(namespaces) == 0 {
+			if len(sdConfig.Namespaces.Names) == 0 {
+				return true
+			}
+			continue
+		}
+		discovered := map[string]bool{}
+		for _, name := range sdConfig.Namespaces.Names {
+			discovered[name] = true
+		}
+		all := true
+		for _, namespace := range namespaces {
+			all = all && discovered[namespace]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package ocputils
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const prometheusYaml = `global:
+  scrape_interval: 30s
+scrape_configs:
+- job_name: serviceMonitor/nvidia-gpu-operator/nvidia-dcgm-exporter/0
+  honor_labels: false
+  kubernetes_sd_configs:
+  - role: endpoints
+    namespaces:
+      names:
+      - nvidia-gpu-operator
+  scrape_interval: 1s
+  relabel_configs:
+  - source_labels:
+    - job
+    target_label: __tmp_prometheus_job_name
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_service_label_app
+    regex: nvidia-dcgm-exporter
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_endpoint_port_name
+    regex: gpu-metrics
+- job_name: serviceMonitor/openshift-monitoring/node-exporter/0
+  kubernetes_sd_configs:
+  - role: endpointslice
+    namespaces:
+      names:
+      - openshift-monitoring
+  relabel_configs:
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_endpointslice_port_name
+    regex: https
+- job_name: serviceMonitor/nvidia-gpu-operator/gpu-operator/0
+  kubernetes_sd_configs:
+  - role: endpoints
+  relabel_configs:
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_pod_container_port_name
+    regex: metrics
+- job_name: serviceMonitor/nvidia-gpu-operator/gpu-operator/1
+  kubernetes_sd_configs:
+  - role: endpoints
+    namespaces:
+      names:
+      - nvidia-gpu-operator
+      - openshift-monitoring
+  relabel_configs:
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_pod_container_port_number
+    regex: "8080"
+- job_name: podMonitor/openshift-monitoring/some-pods/0
+`
+
+func TestPrometheusConfigServiceMonitorJobs(t *testing.T) {
+	promConfig, err := ParsePrometheusConfig([]byte(prometheusYaml))
+	if err != nil {
+		t.Fatalf("ParsePrometheusConfig failed: %v", err)
+	}
+	jobs := promConfig.ServiceMonitorJobs()
+	if len(jobs) != 4 {
+		t.Fatalf("expected 4 ServiceMonitor jobs, got %v", jobs)
+	}
+	operatorNamespace := []string{"nvidia-gpu-operator"}
+	job, err := promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "nvidia-dcgm-exporter", port("gpu-metrics"), operatorNamespace)
+	if err != nil {
+		t.Fatalf("FindServiceMonitorJob failed: %v", err)
+	}
+	if job.Endpoint != 0 || len(job.RelabelConfigs) != 3 {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if _, err := promConfig.FindServiceMonitorJob("openshift-monitoring", "node-exporter", port("https"), []string{"openshift-monitoring"}); err != nil {
+		t.Errorf("endpointslice job not found: %v", err)
+	}
+	if _, err := promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "nvidia-dcgm-exporter", port("metrics"), operatorNamespace); err == nil {
+		t.Errorf("a job was found for a port the ServiceMonitor does not scrape")
+	}
+	if _, err := promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "nvidia-dcgm-exporter", port("gpu-metrics"), nil); err == nil {
+		t.Errorf("a job of one namespace was found for any namespace")
+	}
+	if _, err := promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "node-status-exporter", port("metrics"), operatorNamespace); err == nil {
+		t.Errorf("a job was found for a missing ServiceMonitor")
+	}
+}
+
+func TestPrometheusConfigTargetPortJobs(t *testing.T) {
+	promConfig, err := ParsePrometheusConfig([]byte(prometheusYaml))
+	if err != nil {
+		t.Fatalf("ParsePrometheusConfig failed: %v", err)
+	}
+	byName := intstr.FromString("metrics")
+	job, err := promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "gpu-operator", ServiceMonitorEndpoint{TargetPort: &byName}, nil)
+	if err != nil {
+		t.Fatalf("job of a named targetPort in any namespace not found: %v", err)
+	}
+	if job.Endpoint != 0 {
+		t.Errorf("unexpected job %+v", job)
+	}
+	byNumber := intstr.FromInt(8080)
+	namespaces := []string{"openshift-monitoring", "nvidia-gpu-operator"}
+	job, err = promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "gpu-operator", ServiceMonitorEndpoint{TargetPort: &byNumber}, namespaces)
+	if err != nil {
+		t.Fatalf("job of a numbered targetPort not found: %v", err)
+	}
+	if job.Endpoint != 1 {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if _, err := promConfig.FindServiceMonitorJob("nvidia-gpu-operator", "gpu-operator", ServiceMonitorEndpoint{TargetPort: &byNumber}, append(namespaces, "default")); err == nil {
+		t.Errorf("a job was found for a namespace it does not discover")
+	}
+}
+
+func port(name string) ServiceMonitorEndpoint {
+	return ServiceMonitorEndpoint{Port: name}
+}
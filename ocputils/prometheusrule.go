@@ -0,0 +1,59 @@
+package ocputils
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+var prometheusRuleResource = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"}
+
+// PrometheusRule holds the fields of a monitoring.coreos.com/v1 PrometheusRule.
+type PrometheusRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		Groups []PrometheusRuleSpecGroup `json:"groups"`
+	} `json:"spec"`
+}
+
+type PrometheusRuleSpecGroup struct {
+	Name  string     `json:"name"`
+	Rules []RuleSpec `json:"rules"`
+}
+
+// RuleSpec is an alerting rule when Alert is set, a recording rule otherwise.
+type RuleSpec struct {
+	Alert       string             `json:"alert,omitempty"`
+	Record      string             `json:"record,omitempty"`
+	Expr        intstr.IntOrString `json:"expr"`
+	For         string             `json:"for,omitempty"`
+	Labels      map[string]string  `json:"labels,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+}
+
+func GetPrometheusRules(config *rest.Config, namespace string) ([]PrometheusRule, error) {
+	dClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := dClient.Resource(prometheusRuleResource).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rules := []PrometheusRule{}
+	for _, item := range resp.Items {
+		rule := PrometheusRule{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), &rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
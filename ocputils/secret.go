@@ -3,22 +3,15 @@ package ocputils
 import (
 	"bytes"
 	"compress/gzip"
-	"context"
 	"fmt"
 	"io"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 func GetSecret(config *rest.Config, namespace string, name string) (*corev1.Secret, error) {
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	return Get[corev1.Secret](config, namespace, name)
 }
 
 func GetSecretValue(secret *corev1.Secret, data string, isGziped bool) (*string, error) {
@@ -0,0 +1,77 @@
+package ocputils
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var serviceMonitorResource = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"}
+
+// ServiceMonitor holds the fields of a monitoring.coreos.com/v1 ServiceMonitor
+// the tests check, the Prometheus operator API is not vendored.
+type ServiceMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ServiceMonitorSpec `json:"spec"`
+}
+
+type ServiceMonitorSpec struct {
+	Selector          metav1.LabelSelector `json:"selector"`
+	NamespaceSelector struct {
+		Any        bool     `json:"any,omitempty"`
+		MatchNames []string `json:"matchNames,omitempty"`
+	} `json:"namespaceSelector,omitempty"`
+	Endpoints []ServiceMonitorEndpoint `json:"endpoints"`
+}
+
+type ServiceMonitorEndpoint struct {
+	Port       string              `json:"port,omitempty"`
+	TargetPort *intstr.IntOrString `json:"targetPort,omitempty"`
+	Path       string              `json:"path,omitempty"`
+	Scheme     string              `json:"scheme,omitempty"`
+	Interval   string              `json:"interval,omitempty"`
+}
+
+// String returns the named Service port of the endpoint, or its targetPort.
+func (e ServiceMonitorEndpoint) String() string {
+	if len(e.Port) > 0 || e.TargetPort == nil {
+		return e.Port
+	}
+	return fmt.Sprintf("targetPort %v", e.TargetPort.String())
+}
+
+func GetServiceMonitor(config *rest.Config, namespace string, name string) (*ServiceMonitor, error) {
+	dClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := dClient.Resource(serviceMonitorResource).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	sm := &ServiceMonitor{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(resp.UnstructuredContent(), sm)
+	if err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+func GetServicesByLabel(config *rest.Config, namespace string, labelSelector string) (*corev1.ServiceList, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return clientset.CoreV1().Services(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+}
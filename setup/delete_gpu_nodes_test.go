@@ -0,0 +1,82 @@
+package setup
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinesetv1b1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+var _ = Describe("delete_gpu_nodes : ", Ordered, func() {
+
+	var (
+		config      *rest.Config
+		namespace   string
+		machineSets []machinesetv1b1.MachineSet
+	)
+
+	BeforeAll(func() {
+		namespace = internal.MachineApiNamespace
+		config = internal.GetClientConfig()
+	})
+
+	It("find the MachineSets created by scale_gpu_nodes", func() {
+		list, err := ocputils.GetMachineSetsByLabel(config, namespace, fmt.Sprintf("%v=%v", ocputils.CiMachineSetLabel, ocputils.CiMachineSetLabelValue))
+		Expect(err).ToNot(HaveOccurred())
+		if len(list.Items) == 0 {
+			Skip("No MachineSet created by scale_gpu_nodes")
+		}
+		machineSets = list.Items
+		for _, ms := range machineSets {
+			testutils.Printf("Info", "Deleting MachineSet %v", ms.Name)
+			_ = testutils.SaveAsJsonToArtifactsDir(ms, fmt.Sprintf("deleted_machineset-%v.json", ms.Name))
+		}
+	})
+
+	It("scale the MachineSets down", func() {
+		for i, ms := range machineSets {
+			patched, err := ocputils.ReconcileMachineSetReplicas(config, &ms, 0)
+			Expect(err).ToNot(HaveOccurred())
+			machineSets[i] = *patched
+		}
+	})
+
+	It("wait for the machines to be deleted", func() {
+		for _, ms := range machineSets {
+			selector := fmt.Sprintf("%v=%v", ocputils.MachineSetLabel, ms.Name)
+			err := testutils.ExecWithRetryBackoff(fmt.Sprintf("Wait for the machines of %v to be deleted", ms.Name), func() bool {
+				machines, err := ocputils.GetMachinesByLabel(config, namespace, selector)
+				if err != nil {
+					testutils.Printf("Error", "%v", err)
+					return false
+				}
+				for _, machine := range machines.Items {
+					phase := ""
+					if machine.Status.Phase != nil {
+						phase = *machine.Status.Phase
+					}
+					testutils.Printf("Info", "Machine %v is %v", machine.Name, phase)
+				}
+				return len(machines.Items) == 0
+			}, 30, 30*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("delete the MachineSets", func() {
+		for _, ms := range machineSets {
+			err := ocputils.DeleteMachineSet(config, namespace, ms.Name)
+			if !errors.IsNotFound(err) {
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+	})
+})
@@ -96,6 +96,8 @@ var _ = Describe("deploy_gpu_operator :", Ordered, func() {
 			Expect(err).ToNot(HaveOccurred())
 			err = testutils.SaveAsJsonToArtifactsDir(sub, "gpu_operator_subscription.json")
 			Expect(err).ToNot(HaveOccurred())
+			err = testutils.SaveToArtifactsDir([]byte(gpuOpChannel), "gpu_operator_channel.txt")
+			Expect(err).ToNot(HaveOccurred())
 		})
 	})
 
@@ -8,7 +8,6 @@ import (
 	. "github.com/onsi/gomega"
 	nfdv1 "github.com/openshift/cluster-nfd-operator/api/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 
 	"ci-tools-nvidia-gpu-operator/internal"
@@ -16,10 +15,7 @@ import (
 	"ci-tools-nvidia-gpu-operator/testutils"
 )
 
-const (
-	nfdCrName   = "nfd-cr-testing"
-	nfdResource = "nodefeaturediscoveries"
-)
+const nfdCrName = "nfd-cr-testing"
 
 var _ = Describe("deploy_nfd_operator :", Ordered, func() {
 	var (
@@ -34,6 +30,9 @@ var _ = Describe("deploy_nfd_operator :", Ordered, func() {
 	)
 
 	BeforeAll(func() {
+		if internal.Config.NfdDeployMode != internal.NfdDeployModeOlm {
+			Skip(fmt.Sprintf("NFD deploy mode is %v", internal.Config.NfdDeployMode))
+		}
 		nfdOpName = "nfd"
 		nfdChannel = "unset"
 		nfdCatalogSource = "unset"
@@ -95,18 +94,13 @@ var _ = Describe("deploy_nfd_operator :", Ordered, func() {
 	})
 
 	It("deploy NFD CR based on alm example", func() {
-		unstructObj, err := getUnstructuredFromAlmExample(nfdAlmExample)
+		nfdCr, err := newNfdCr(nfdAlmExample, internal.Config.NameSpace, nfdCrName, newNfdWorkerOptions())
 		Expect(err).ToNot(HaveOccurred())
-		unstructObj.SetNamespace(internal.Config.NameSpace)
-		unstructObj.SetName(nfdCrName)
-		resp, err := ocputils.CreateDynamicResource(config, nfdv1.GroupVersion.WithResource(nfdResource), unstructObj, internal.Config.NameSpace)
-		Expect(err).ToNot(HaveOccurred())
-		var respNfd nfdv1.NodeFeatureDiscovery = nfdv1.NodeFeatureDiscovery{}
-		err = runtime.DefaultUnstructuredConverter.FromUnstructured(resp.UnstructuredContent(), &respNfd)
+		testutils.Printf("Info", "nfd-worker config:\n%v", nfdCr.Spec.WorkerConfig.ConfigData)
+		respNfd, err := ocputils.Create(config, nfdCr)
 		Expect(err).ToNot(HaveOccurred())
 		err = testutils.SaveAsJsonToArtifactsDir(respNfd, "nfd_cr_create_response.json")
 		Expect(err).ToNot(HaveOccurred())
-
 	})
 
 	It("wait for NFD labels and capture nfd cr state", func() {
@@ -124,8 +118,7 @@ var _ = Describe("deploy_nfd_operator :", Ordered, func() {
 		}
 
 		// Regarless if successful, we want to have the CR in artifacts
-		nfdCr := &nfdv1.NodeFeatureDiscovery{}
-		e := ocputils.GetDynamicResource(config, nfdv1.GroupVersion.WithResource(nfdResource), internal.Config.NameSpace, nfdCrName, nfdCr)
+		nfdCr, e := ocputils.Get[nfdv1.NodeFeatureDiscovery](config, internal.Config.NameSpace, nfdCrName)
 		Expect(e).ToNot(HaveOccurred())
 		e = testutils.SaveAsJsonToArtifactsDir(nfdCr, "nfd_cr.json")
 		Expect(e).ToNot(HaveOccurred())
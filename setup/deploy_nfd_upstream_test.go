@@ -0,0 +1,96 @@
+package setup
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+// Focused with deploy_nfd_operator, NFD_DEPLOY_MODE picks this container or
+// the OLM one
+var _ = Describe("deploy_nfd_operator : upstream manifests", Ordered, func() {
+	var (
+		config *rest.Config
+		objs   []*unstructured.Unstructured
+	)
+
+	BeforeAll(func() {
+		if internal.Config.NfdDeployMode != internal.NfdDeployModeUpstream {
+			Skip(fmt.Sprintf("NFD deploy mode is %v", internal.Config.NfdDeployMode))
+		}
+		testutils.AddDiagnosticsNamespace(internal.UpstreamNfdNamespace)
+		config = internal.GetClientConfig()
+	})
+
+	It("render the upstream NFD manifests", func() {
+		crds, err := getUpstreamNfdCrds()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(crds).ToNot(BeEmpty(), "No CRD in %v", upstreamNfdCrdsUrl)
+		objs, err = newUpstreamNfdObjects(newNfdWorkerOptions())
+		Expect(err).ToNot(HaveOccurred())
+		objs = append(crds, objs...)
+		testutils.Printf("Info", "NFD %v, %d objects", internal.UpstreamNfdVersion, len(objs))
+		err = testutils.SaveToArtifactsDir([]byte(internal.UpstreamNfdVersion), "nfd_version.txt")
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveAsJsonToArtifactsDir(objs, "nfd_upstream_manifests.json")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("apply the upstream NFD objects", func() {
+		// Take over the fields set by a previous install, e.g. with kubectl,
+		// but keep the CRDs of an existing NFD as they are
+		results, err := ocputils.ApplyObjects(config, ocputils.ApplyOptions{Force: true, KeepCRDs: true}, objs)
+		for _, result := range results {
+			testutils.Printf("Apply", "%v", result)
+		}
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("wait for the nfd-master Deployment", func() {
+		var deployment *appsv1.Deployment
+		err := testutils.ExecWithRetryBackoff("Wait for the nfd-master Deployment", func() bool {
+			d, err := ocputils.GetDeployment(config, internal.UpstreamNfdNamespace, internal.UpstreamNfdMaster)
+			if err != nil {
+				return false
+			}
+			deployment = d
+			return ocputils.DeploymentReady(deployment)
+		}, 20, 15*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(deployment, "nfd_master_deployment.json")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("wait for the nfd-worker DaemonSet", func() {
+		var ds *appsv1.DaemonSet
+		err := testutils.ExecWithRetryBackoff("Wait for the nfd-worker DaemonSet", func() bool {
+			d, err := ocputils.GetDaemonset(config, internal.UpstreamNfdNamespace, internal.UpstreamNfdWorker)
+			if err != nil {
+				return false
+			}
+			ds = d
+			return ocputils.DaemonSetReady(ds)
+		}, 20, 15*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(ds, "nfd_worker_daemonset.json")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("wait for NFD labels", func() {
+		err := testutils.ExecWithRetryBackoff("wait for NFD labels", func() bool {
+			nodes, err := ocputils.GetNodesByLabel(config, "feature.node.kubernetes.io/system-os_release.ID")
+			if err != nil {
+				return false
+			}
+			return len(nodes.Items) > 0
+		}, 20, 30*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
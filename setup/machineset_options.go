@@ -0,0 +1,195 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+)
+
+// gpuMachineSetOptions are applied to the GPU MachineSet when it is created.
+type gpuMachineSetOptions struct {
+	Taints     []corev1.Taint
+	NodeLabels map[string]string
+	// Zone of the worker MachineSet the GPU MachineSet is cloned from. The
+	// GPU capacity of the zones is not known, the first worker MachineSet is
+	// cloned when empty: set GPU_ZONE to a zone offering the instance type.
+	Zone string
+	Spot bool
+	// RootVolumeSize in GiB, the driver build needs room for the kernel headers
+	RootVolumeSize int64
+}
+
+func newGpuMachineSetOptions() (*gpuMachineSetOptions, error) {
+	opts := &gpuMachineSetOptions{Zone: internal.Config.CiMachineSetZone}
+	var err error
+	if opts.Taints, err = parseTaints(internal.Config.CiMachineSetTaints); err != nil {
+		return nil, err
+	}
+	if opts.NodeLabels, err = parseLabels(internal.Config.CiMachineSetNodeLabels); err != nil {
+		return nil, err
+	}
+	if len(internal.Config.CiMachineSetSpot) > 0 {
+		if opts.Spot, err = strconv.ParseBool(internal.Config.CiMachineSetSpot); err != nil {
+			return nil, fmt.Errorf("invalid GPU_SPOT %q: %v", internal.Config.CiMachineSetSpot, err)
+		}
+	}
+	if len(internal.Config.CiMachineSetRootVolumeSize) > 0 {
+		opts.RootVolumeSize, err = strconv.ParseInt(internal.Config.CiMachineSetRootVolumeSize, 10, 64)
+		if err != nil || opts.RootVolumeSize <= 0 {
+			return nil, fmt.Errorf("invalid GPU_ROOT_VOLUME_SIZE %q", internal.Config.CiMachineSetRootVolumeSize)
+		}
+	}
+	return opts, nil
+}
+
+// parseTaints parses comma-separated taints in the kubectl taint format,
+// key[=value]:effect, e.g. "nvidia.com/gpu=present:NoSchedule".
+func parseTaints(taints string) ([]corev1.Taint, error) {
+	parsed := []corev1.Taint{}
+	for _, taint := range strings.Split(taints, ",") {
+		taint = strings.TrimSpace(taint)
+		if len(taint) == 0 {
+			continue
+		}
+		keyValue, effect, ok := strings.Cut(taint, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid taint %q, expected key[=value]:effect", taint)
+		}
+		switch corev1.TaintEffect(effect) {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return nil, fmt.Errorf("invalid taint effect %q of %q", effect, taint)
+		}
+		key, value, _ := strings.Cut(keyValue, "=")
+		if len(key) == 0 {
+			return nil, fmt.Errorf("invalid taint %q, missing key", taint)
+		}
+		parsed = append(parsed, corev1.Taint{Key: key, Value: value, Effect: corev1.TaintEffect(effect)})
+	}
+	return parsed, nil
+}
+
+// parseLabels parses comma-separated key=value labels.
+func parseLabels(labels string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, label := range strings.Split(labels, ",") {
+		label = strings.TrimSpace(label)
+		if len(label) == 0 {
+			continue
+		}
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || len(key) == 0 {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", label)
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
+
+// apply sets the options on the MachineSet template. The providerSpec options
+// depend on the platform.
+func (opts *gpuMachineSetOptions) apply(ms *machinev1beta1.MachineSet, provider gpuMachineProvider) error {
+	spec, err := getProviderSpec(ms)
+	if err != nil {
+		return err
+	}
+	if opts.Spot {
+		if err := provider.SetSpot(spec); err != nil {
+			return err
+		}
+	}
+	if opts.RootVolumeSize > 0 {
+		if err := provider.SetRootVolumeSize(spec, opts.RootVolumeSize); err != nil {
+			return err
+		}
+	}
+	if err := setProviderSpec(ms, spec); err != nil {
+		return err
+	}
+	for _, taint := range opts.Taints {
+		if !hasTaint(ms.Spec.Template.Spec.Taints, taint) {
+			ms.Spec.Template.Spec.Taints = append(ms.Spec.Template.Spec.Taints, taint)
+		}
+	}
+	if len(opts.NodeLabels) > 0 && ms.Spec.Template.Spec.ObjectMeta.Labels == nil {
+		ms.Spec.Template.Spec.ObjectMeta.Labels = map[string]string{}
+	}
+	for key, value := range opts.NodeLabels {
+		ms.Spec.Template.Spec.ObjectMeta.Labels[key] = value
+	}
+	return nil
+}
+
+// differences returns the options missing from an existing GPU MachineSet,
+// which is reused as it is.
+func (opts *gpuMachineSetOptions) differences(ms *machinev1beta1.MachineSet, provider gpuMachineProvider) ([]string, error) {
+	diffs := []string{}
+	for _, taint := range opts.Taints {
+		if !hasTaint(ms.Spec.Template.Spec.Taints, taint) {
+			diffs = append(diffs, fmt.Sprintf("taint %v", taint.ToString()))
+		}
+	}
+	for key, value := range opts.NodeLabels {
+		if v, ok := ms.Spec.Template.Spec.ObjectMeta.Labels[key]; !ok || v != value {
+			diffs = append(diffs, fmt.Sprintf("node label %v=%v", key, value))
+		}
+	}
+	if opts.Spot {
+		changed, err := changesProviderSpec(ms, provider.SetSpot)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			diffs = append(diffs, "spot instances")
+		}
+	}
+	if opts.RootVolumeSize > 0 {
+		changed, err := changesProviderSpec(ms, func(spec providerSpec) error {
+			return provider.SetRootVolumeSize(spec, opts.RootVolumeSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			diffs = append(diffs, fmt.Sprintf("root volume size %vGiB", opts.RootVolumeSize))
+		}
+	}
+	return diffs, nil
+}
+
+// changesProviderSpec returns whether set changes the providerSpec of ms. The
+// specs are compared as JSON, the numbers of the decoded spec are float64.
+func changesProviderSpec(ms *machinev1beta1.MachineSet, set func(providerSpec) error) (bool, error) {
+	spec, err := getProviderSpec(ms)
+	if err != nil {
+		return false, err
+	}
+	before, err := json.Marshal(spec)
+	if err != nil {
+		return false, err
+	}
+	if err := set(spec); err != nil {
+		return false, err
+	}
+	after, err := json.Marshal(spec)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(before, after), nil
+}
+
+func hasTaint(taints []corev1.Taint, taint corev1.Taint) bool {
+	for _, t := range taints {
+		if t.MatchTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
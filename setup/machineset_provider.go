@@ -0,0 +1,284 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+)
+
+const maxMachineSetNameSize = 63
+
+// providerSpec is the providerSpec of a MachineSet. It is edited as a map and
+// not as the typed provider config, so that fields unknown to the vendored
+// API are kept.
+type providerSpec map[string]interface{}
+
+// gpuMachineProvider patches the providerSpec of a worker MachineSet of a
+// platform to provision GPU nodes.
+type gpuMachineProvider interface {
+	// DefaultInstanceType is used when GPU_INSTANCE_TYPE is not set.
+	DefaultInstanceType() string
+	// Matches returns whether the providerSpec provisions GPU nodes of instanceType.
+	Matches(spec providerSpec, instanceType string) bool
+	// SetInstanceType sets the providerSpec fields for GPU nodes of instanceType.
+	SetInstanceType(spec providerSpec, instanceType string) error
+	// Zone returns the availability zone of the providerSpec, empty when the
+	// platform has no zones.
+	Zone(spec providerSpec) string
+	// SetSpot requests spot, or preemptible, instances.
+	SetSpot(spec providerSpec) error
+	// SetRootVolumeSize sets the size of the root volume in GiB.
+	SetRootVolumeSize(spec providerSpec, sizeGiB int64) error
+}
+
+// newGpuMachineProvider returns the provider of the platform of the
+// Infrastructure CR.
+func newGpuMachineProvider(platform configv1.PlatformType, acceleratorType string, acceleratorCount string) (gpuMachineProvider, error) {
+	switch platform {
+	case configv1.AWSPlatformType:
+		return &awsProvider{}, nil
+	case configv1.AzurePlatformType:
+		return &azureProvider{}, nil
+	case configv1.GCPPlatformType:
+		count, err := strconv.ParseInt(acceleratorCount, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU accelerator count %q: %v", acceleratorCount, err)
+		}
+		return &gcpProvider{acceleratorType: acceleratorType, acceleratorCount: count}, nil
+	case configv1.VSpherePlatformType:
+		return &vsphereProvider{}, nil
+	}
+	return nil, fmt.Errorf("GPU MachineSets are not supported on platform %q", platform)
+}
+
+// specMap returns the object field of spec, created when missing.
+func specMap(spec providerSpec, field string) map[string]interface{} {
+	m, ok := spec[field].(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		spec[field] = m
+	}
+	return m
+}
+
+type awsProvider struct{}
+
+func (p *awsProvider) DefaultInstanceType() string {
+	return "g4dn.xlarge"
+}
+
+func (p *awsProvider) Matches(spec providerSpec, instanceType string) bool {
+	return spec["instanceType"] == instanceType
+}
+
+func (p *awsProvider) SetInstanceType(spec providerSpec, instanceType string) error {
+	spec["instanceType"] = instanceType
+	return nil
+}
+
+func (p *awsProvider) Zone(spec providerSpec) string {
+	zone, _ := specMap(spec, "placement")["availabilityZone"].(string)
+	return zone
+}
+
+func (p *awsProvider) SetSpot(spec providerSpec) error {
+	// An empty maxPrice is capped at the on-demand price
+	spec["spotMarketOptions"] = map[string]interface{}{}
+	return nil
+}
+
+// SetRootVolumeSize resizes the block device without device name, which is
+// the root volume.
+func (p *awsProvider) SetRootVolumeSize(spec providerSpec, sizeGiB int64) error {
+	devices, _ := spec["blockDevices"].([]interface{})
+	for _, device := range devices {
+		device, ok := device.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := device["deviceName"]; ok {
+			continue
+		}
+		specMap(device, "ebs")["volumeSize"] = sizeGiB
+		return nil
+	}
+	spec["blockDevices"] = append(devices, map[string]interface{}{
+		"ebs": map[string]interface{}{"volumeSize": sizeGiB, "volumeType": "gp3"},
+	})
+	return nil
+}
+
+type azureProvider struct{}
+
+func (p *azureProvider) DefaultInstanceType() string {
+	return "Standard_NC4as_T4_v3"
+}
+
+func (p *azureProvider) Matches(spec providerSpec, instanceType string) bool {
+	return spec["vmSize"] == instanceType
+}
+
+func (p *azureProvider) SetInstanceType(spec providerSpec, instanceType string) error {
+	spec["vmSize"] = instanceType
+	return nil
+}
+
+func (p *azureProvider) Zone(spec providerSpec) string {
+	zone, _ := spec["zone"].(string)
+	return zone
+}
+
+func (p *azureProvider) SetSpot(spec providerSpec) error {
+	spec["spotVMOptions"] = map[string]interface{}{}
+	return nil
+}
+
+func (p *azureProvider) SetRootVolumeSize(spec providerSpec, sizeGiB int64) error {
+	specMap(spec, "osDisk")["diskSizeGB"] = sizeGiB
+	return nil
+}
+
+// gcpProvider attaches guest accelerators to N1 machine types, accelerator
+// optimized machine types (A2, A3, G2) come with their GPUs. GCP requires
+// GPU instances to terminate on host maintenance.
+type gcpProvider struct {
+	acceleratorType  string
+	acceleratorCount int64
+}
+
+func gcpHasBuiltinGpus(machineType string) bool {
+	for _, family := range []string{"a2-", "a3-", "g2-"} {
+		if strings.HasPrefix(machineType, family) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *gcpProvider) DefaultInstanceType() string {
+	return "n1-standard-4"
+}
+
+func (p *gcpProvider) Matches(spec providerSpec, instanceType string) bool {
+	if spec["machineType"] != instanceType {
+		return false
+	}
+	if gcpHasBuiltinGpus(instanceType) {
+		return true
+	}
+	gpus, _ := spec["gpus"].([]interface{})
+	for _, gpu := range gpus {
+		if gpu, ok := gpu.(map[string]interface{}); ok && gpu["type"] == p.acceleratorType {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *gcpProvider) SetInstanceType(spec providerSpec, instanceType string) error {
+	spec["machineType"] = instanceType
+	spec["onHostMaintenance"] = string(machinev1beta1.TerminateHostMaintenanceType)
+	if gcpHasBuiltinGpus(instanceType) {
+		delete(spec, "gpus")
+		return nil
+	}
+	if len(p.acceleratorType) == 0 {
+		return fmt.Errorf("machine type %v needs a GPU accelerator type, set GPU_ACCELERATOR_TYPE", instanceType)
+	}
+	spec["gpus"] = []interface{}{
+		map[string]interface{}{"type": p.acceleratorType, "count": p.acceleratorCount},
+	}
+	return nil
+}
+
+func (p *gcpProvider) Zone(spec providerSpec) string {
+	zone, _ := spec["zone"].(string)
+	return zone
+}
+
+func (p *gcpProvider) SetSpot(spec providerSpec) error {
+	spec["preemptible"] = true
+	return nil
+}
+
+func (p *gcpProvider) SetRootVolumeSize(spec providerSpec, sizeGiB int64) error {
+	disks, _ := spec["disks"].([]interface{})
+	for _, disk := range disks {
+		if disk, ok := disk.(map[string]interface{}); ok && disk["boot"] == true {
+			disk["sizeGb"] = sizeGiB
+			return nil
+		}
+	}
+	return fmt.Errorf("no boot disk in the providerSpec")
+}
+
+// vsphereProvider clones the machines from a VM template: the Machine API
+// has no PCI device field for vSphere, the GPU is passed through by the
+// template.
+type vsphereProvider struct{}
+
+func (p *vsphereProvider) DefaultInstanceType() string {
+	return ""
+}
+
+func (p *vsphereProvider) Matches(spec providerSpec, instanceType string) bool {
+	return spec["template"] == instanceType
+}
+
+func (p *vsphereProvider) SetInstanceType(spec providerSpec, instanceType string) error {
+	spec["template"] = instanceType
+	return nil
+}
+
+func (p *vsphereProvider) Zone(spec providerSpec) string {
+	return ""
+}
+
+func (p *vsphereProvider) SetSpot(spec providerSpec) error {
+	return fmt.Errorf("spot instances are not available on vSphere")
+}
+
+func (p *vsphereProvider) SetRootVolumeSize(spec providerSpec, sizeGiB int64) error {
+	spec["diskGiB"] = sizeGiB
+	return nil
+}
+
+func getProviderSpec(ms *machinev1beta1.MachineSet) (providerSpec, error) {
+	spec := providerSpec{}
+	if ms.Spec.Template.Spec.ProviderSpec.Value == nil {
+		return nil, fmt.Errorf("MachineSet %v has no providerSpec", ms.Name)
+	}
+	b, err := json.Marshal(ms.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func setProviderSpec(ms *machinev1beta1.MachineSet, spec providerSpec) error {
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, ms.Spec.Template.Spec.ProviderSpec.Value)
+}
+
+// gpuMachineSetName returns the name of the GPU MachineSet cloned from base,
+// instance types are not always valid in names, e.g. g4dn.xlarge or
+// Standard_NC4as_T4_v3. The name is a label value of the machines, limited to
+// 63 characters.
+func gpuMachineSetName(base string, instanceType string) string {
+	suffix := strings.ToLower(strings.NewReplacer(".", "-", "_", "-", "/", "-").Replace(instanceType))
+	name := fmt.Sprintf("%v-%v", base, suffix)
+	if len(name) > maxMachineSetNameSize {
+		name = strings.TrimRight(name[:maxMachineSetNameSize], "-")
+	}
+	return name
+}
@@ -0,0 +1,108 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	nfdv1 "github.com/openshift/cluster-nfd-operator/api/v1"
+	"sigs.k8s.io/yaml"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+)
+
+// nfdWorkerOptions are set in the nfd-worker config of the NFD CR, empty
+// options keep the values of the alm-example.
+type nfdWorkerOptions struct {
+	// PciDeviceClasses are the PCI device classes labeled by NFD, e.g. 03 for
+	// display controllers, or 0302 for 3D controllers only
+	PciDeviceClasses []string
+	// PciLabelFields are the fields of the PCI labels, among class, vendor,
+	// device, subsystem_vendor and subsystem_device
+	PciLabelFields []string
+	// LabelSources enable the feature sources, e.g. pci, kernel, system
+	LabelSources []string
+}
+
+func splitList(list string) []string {
+	values := []string{}
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func newNfdWorkerOptions() nfdWorkerOptions {
+	return nfdWorkerOptions{
+		PciDeviceClasses: splitList(internal.Config.NfdPciDeviceClasses),
+		PciLabelFields:   splitList(internal.Config.NfdPciLabelFields),
+		LabelSources:     splitList(internal.Config.NfdLabelSources),
+	}
+}
+
+// subMap returns the object field of m, created when missing.
+func subMap(m map[string]interface{}, field string) map[string]interface{} {
+	sub, ok := m[field].(map[string]interface{})
+	if !ok {
+		sub = map[string]interface{}{}
+		m[field] = sub
+	}
+	return sub
+}
+
+// apply sets the options in the nfd-worker config, the other settings are kept.
+func (o nfdWorkerOptions) apply(configData string) (string, error) {
+	workerConfig := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(configData), &workerConfig); err != nil {
+		return "", fmt.Errorf("invalid nfd-worker config: %v", err)
+	}
+	if workerConfig == nil {
+		// The alm-example config is often all comments
+		workerConfig = map[string]interface{}{}
+	}
+	pci := subMap(subMap(workerConfig, "sources"), "pci")
+	if len(o.PciDeviceClasses) > 0 {
+		pci["deviceClassWhitelist"] = o.PciDeviceClasses
+	}
+	if len(o.PciLabelFields) > 0 {
+		pci["deviceLabelFields"] = o.PciLabelFields
+	}
+	if len(o.LabelSources) > 0 {
+		subMap(workerConfig, "core")["labelSources"] = o.LabelSources
+	}
+	data, err := yaml.Marshal(workerConfig)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// newNfdCr returns the NodeFeatureDiscovery alm-example of the NFD CSV, the
+// operand image matches the operator version, with opts in its worker config.
+func newNfdCr(almExample string, namespace string, name string, opts nfdWorkerOptions) (*nfdv1.NodeFeatureDiscovery, error) {
+	examples := []nfdv1.NodeFeatureDiscovery{}
+	if err := json.Unmarshal([]byte(almExample), &examples); err != nil {
+		return nil, err
+	}
+	var cr *nfdv1.NodeFeatureDiscovery
+	for i := range examples {
+		// Recent alm-examples have a NodeFeatureRule as well
+		if examples[i].Kind == "NodeFeatureDiscovery" {
+			cr = examples[i].DeepCopy()
+			break
+		}
+	}
+	if cr == nil {
+		return nil, fmt.Errorf("no NodeFeatureDiscovery in the alm examples")
+	}
+	cr.Namespace = namespace
+	cr.Name = name
+	configData, err := opts.apply(cr.Spec.WorkerConfig.ConfigData)
+	if err != nil {
+		return nil, err
+	}
+	cr.Spec.WorkerConfig.ConfigData = configData
+	return cr, nil
+}
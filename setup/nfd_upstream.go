@@ -0,0 +1,76 @@
+package setup
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+)
+
+// The nfd-worker config of the upstream NFD manifests
+const (
+	upstreamNfdWorkerConf = "nfd-worker-conf"
+	upstreamNfdWorkerKey  = "nfd-worker.conf"
+)
+
+// upstreamNfdCrdsUrl is the unmodified NodeFeature and NodeFeatureRule CRDs
+// of internal.UpstreamNfdVersion
+var upstreamNfdCrdsUrl = fmt.Sprintf("https://raw.githubusercontent.com/kubernetes-sigs/node-feature-discovery/%v/deployment/base/nfd-crds/nfd-api-crds.yaml", internal.UpstreamNfdVersion)
+
+const upstreamNfdCrdsTimeout = time.Minute
+
+// upstreamNfdManifests are the manifests of internal.UpstreamNfdVersion
+//
+//go:embed manifests/nfd-v0.14.6.yaml
+var upstreamNfdManifests []byte
+
+// newUpstreamNfdObjects returns the objects of the upstream NFD manifests,
+// with opts in the nfd-worker config.
+func newUpstreamNfdObjects(opts nfdWorkerOptions) ([]*unstructured.Unstructured, error) {
+	objs, err := ocputils.DecodeManifests(upstreamNfdManifests)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if obj.GetKind() != "ConfigMap" || obj.GetName() != upstreamNfdWorkerConf {
+			continue
+		}
+		configData, _, err := unstructured.NestedString(obj.Object, "data", upstreamNfdWorkerKey)
+		if err != nil {
+			return nil, err
+		}
+		configData, err = opts.apply(configData)
+		if err != nil {
+			return nil, err
+		}
+		if err := unstructured.SetNestedField(obj.Object, configData, "data", upstreamNfdWorkerKey); err != nil {
+			return nil, err
+		}
+		return objs, nil
+	}
+	return nil, fmt.Errorf("no %v ConfigMap in the NFD %v manifests", upstreamNfdWorkerConf, internal.UpstreamNfdVersion)
+}
+
+// getUpstreamNfdCrds downloads the CRDs of the upstream NFD release.
+func getUpstreamNfdCrds() ([]*unstructured.Unstructured, error) {
+	client := &http.Client{Timeout: upstreamNfdCrdsTimeout}
+	resp, err := client.Get(upstreamNfdCrdsUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %v: %v", upstreamNfdCrdsUrl, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ocputils.DecodeManifests(data)
+}
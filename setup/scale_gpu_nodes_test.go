@@ -0,0 +1,179 @@
+package setup
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinesetv1b1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+var _ = Describe("scale_gpu_nodes : ", Ordered, func() {
+
+	var (
+		config        *rest.Config
+		gpuMachineset *machinesetv1b1.MachineSet
+		provider      gpuMachineProvider
+		opts          *gpuMachineSetOptions
+		instanceType  string
+		namespace     string
+		replicas      int32
+	)
+
+	BeforeAll(func() {
+		instanceType = internal.Config.CiMachineSetInstanceType
+		namespace = internal.MachineApiNamespace
+
+		config = internal.GetClientConfig()
+
+		r, err := strconv.ParseInt(internal.Config.CiMachineSetReplicas, 10, 32)
+		Expect(err).ToNot(HaveOccurred(), "Invalid replicas value")
+		replicas = int32(r)
+	})
+
+	It("detect the cluster platform", func() {
+		platform, err := ocputils.GetPlatformType(config)
+		Expect(err).ToNot(HaveOccurred())
+		testutils.Printf("Info", "Cluster platform is %v", platform)
+		provider, err = newGpuMachineProvider(platform, internal.Config.CiMachineSetAcceleratorType, internal.Config.CiMachineSetAcceleratorCount)
+		Expect(err).ToNot(HaveOccurred())
+		if len(instanceType) == 0 {
+			instanceType = provider.DefaultInstanceType()
+		}
+		Expect(instanceType).ToNot(BeEmpty(), "No default GPU instance type on %v, set GPU_INSTANCE_TYPE", platform)
+		testutils.Printf("Info", "GPU instance type is %v", instanceType)
+		opts, err = newGpuMachineSetOptions()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("ensure a Machineset for desired instance type", func() {
+		workerMs, err := ocputils.GetWorkerMachineSets(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workerMs.Items).ToNot(BeEmpty(), "No worker Machinesets found")
+		for _, ms := range workerMs.Items {
+			fileName := fmt.Sprintf("worker_ms-%v.json", ms.Name)
+			_ = testutils.SaveAsJsonToArtifactsDir(ms, fileName)
+			spec, err := getProviderSpec(&ms)
+			Expect(err).ToNot(HaveOccurred())
+			if provider.Matches(spec, instanceType) && (len(opts.Zone) == 0 || provider.Zone(spec) == opts.Zone) {
+				gpuMachineset = ms.DeepCopy()
+				break
+			}
+		}
+		if gpuMachineset == nil {
+			return
+		}
+		// The options are only applied when the MachineSet is created
+		diffs, err := opts.differences(gpuMachineset, provider)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diffs).To(BeEmpty(), "Machineset %v exists without the requested options, delete it with delete_gpu_nodes", gpuMachineset.Name)
+	})
+
+	It("create a Machineset for desired instance type", func() {
+		if gpuMachineset != nil {
+			Skip("Machineset for desired instance exists. Skipping")
+		}
+
+		workerMs, err := ocputils.GetWorkerMachineSets(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workerMs.Items).ToNot(BeEmpty(), "No worker Machinesets found")
+
+		// The subnets of the MachineSet are specific to its zone, the GPU
+		// MachineSet is cloned from a worker MachineSet of the zone
+		var baseMs *machinesetv1b1.MachineSet
+		zones := []string{}
+		for i, ms := range workerMs.Items {
+			spec, err := getProviderSpec(&ms)
+			Expect(err).ToNot(HaveOccurred())
+			zones = append(zones, provider.Zone(spec))
+			if len(opts.Zone) == 0 || provider.Zone(spec) == opts.Zone {
+				baseMs = &workerMs.Items[i]
+				break
+			}
+		}
+		Expect(baseMs).ToNot(BeNil(), "No worker Machineset in zone %v, found zones %v", opts.Zone, zones)
+		if len(opts.Zone) == 0 {
+			testutils.Printf("Info", "GPU_ZONE is not set, using zone %v of the first worker Machineset, whatever its %v capacity", zones[0], instanceType)
+		}
+
+		ms := &machinesetv1b1.MachineSet{
+			ObjectMeta: *baseMs.ObjectMeta.DeepCopy(),
+			Spec:       *baseMs.Spec.DeepCopy(),
+		}
+		testutils.Printf("Info", "Using Machineset %v as base for new machineset", ms.Name)
+		// Change meta
+		ms.ObjectMeta.Name = gpuMachineSetName(ms.Name, instanceType)
+		ms.ObjectMeta.UID = ""
+		ms.ObjectMeta.ResourceVersion = ""
+		if ms.ObjectMeta.Labels == nil {
+			ms.ObjectMeta.Labels = map[string]string{}
+		}
+		ms.ObjectMeta.Labels[ocputils.CiMachineSetLabel] = ocputils.CiMachineSetLabelValue
+		// The capacity annotations are those of the base instance type, the
+		// Machine API sets them for the GPU instance type. The cluster
+		// autoscaler would scale from zero with a wrong GPU count.
+		for _, annotation := range ocputils.MachineSetCapacityAnnotations {
+			delete(ms.ObjectMeta.Annotations, annotation)
+		}
+		// chenge spec labels
+		ms.Spec.Selector.MatchLabels[ocputils.MachineSetLabel] = ms.ObjectMeta.Name
+		ms.Spec.Template.ObjectMeta.Labels[ocputils.MachineSetLabel] = ms.ObjectMeta.Name
+		// Change instance type
+		spec, err := getProviderSpec(ms)
+		Expect(err).ToNot(HaveOccurred())
+		err = provider.SetInstanceType(spec, instanceType)
+		Expect(err).ToNot(HaveOccurred())
+		err = setProviderSpec(ms, spec)
+		Expect(err).ToNot(HaveOccurred())
+		// Taints, node labels, spot and root volume
+		err = opts.apply(ms, provider)
+		Expect(err).ToNot(HaveOccurred())
+		// Set replicas to 1
+		ms.Spec.Replicas = &replicas
+
+		ms, err = ocputils.CreateMachineSet(config, namespace, ms)
+		Expect(err).ToNot(HaveOccurred())
+
+		gpuMachineset = ms
+
+		fileName := fmt.Sprintf("new_machineset-%v.json", ms.Name)
+		err = testutils.SaveAsJsonToArtifactsDir(ms, fileName)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("ensure number of replicas on MachineSet", func() {
+		ms, err := ocputils.ReconcileMachineSetReplicas(config, gpuMachineset, replicas)
+		Expect(err).ToNot(HaveOccurred())
+		gpuMachineset = ms
+	})
+
+	It("wait for GPU MachineSet to become ready", func() {
+		var state *ocputils.MachineSetState
+		var failed error
+		err := testutils.ExecWithRetryBackoff("MachineSet ready state", func() bool {
+			s, err := ocputils.GetMachineSetState(config, namespace, gpuMachineset.Name)
+			if s != nil {
+				state = s
+				gpuMachineset = s.MachineSet
+			}
+			// Failed machines are not retried by the Machine API
+			if _, ok := err.(*ocputils.MachineSetFailedError); ok {
+				failed = err
+				return true
+			}
+			return err == nil && state.Ready
+		}, 30, 30*time.Second)
+		if state != nil {
+			_ = testutils.SaveAsJsonToArtifactsDir(state.Machines, fmt.Sprintf("machines-%v.json", gpuMachineset.Name))
+		}
+		Expect(failed).ToNot(HaveOccurred())
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
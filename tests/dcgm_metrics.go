@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+const (
+	dcgmExporterLabel = "app=nvidia-dcgm-exporter"
+	dcgmMaxSampleAge  = 2 * 60 // seconds
+	burnGpuUtil       = 50     // percent
+)
+
+// dcgmSamplesByNode groups DCGM samples by the node they were collected on.
+// The node is the one of the exporter pod the sample was scraped from, or the
+// Hostname label set by the exporter.
+func dcgmSamplesByNode(config *rest.Config, samples []ocputils.PrometheusSample) (map[string][]ocputils.PrometheusSample, error) {
+	pods, err := ocputils.GetPodsByLabel(config, "", dcgmExporterLabel)
+	if err != nil {
+		return nil, err
+	}
+	podNodes := map[string]string{}
+	for _, pod := range pods.Items {
+		podNodes[pod.Name] = pod.Spec.NodeName
+	}
+	byNode := map[string][]ocputils.PrometheusSample{}
+	for _, sample := range samples {
+		node, ok := podNodes[sample.Metric["pod"]]
+		if !ok {
+			node = sample.Metric["Hostname"]
+		}
+		byNode[node] = append(byNode[node], sample)
+	}
+	return byNode, nil
+}
+
+func getGpuNodeNames(config *rest.Config) ([]string, error) {
+	nodes, err := ocputils.GetNodesByLabel(config, "nvidia.com/gpu.present=true")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
+
+// waitForGpuUtilisation waits until every GPU of every node in nodes reported
+// a utilisation of at least minUtil percent over the last minute.
+func waitForGpuUtilisation(config *rest.Config, prom *ocputils.PrometheusClient, nodes []string, minUtil float64, maxRetries int) (map[string]float64, error) {
+	utilisation := map[string]float64{}
+	err := testutils.ExecWithRetryBackoff("Wait for GPU utilisation to rise", func() bool {
+		samples, err := prom.Query("min_over_time(DCGM_FI_DEV_GPU_UTIL[1m])")
+		if err != nil {
+			testutils.Printf("Error", "%v", err)
+			return false
+		}
+		byNode, err := dcgmSamplesByNode(config, samples)
+		if err != nil {
+			return false
+		}
+		busy := true
+		for _, node := range nodes {
+			nodeSamples, ok := byNode[node]
+			if !ok {
+				busy = false
+				continue
+			}
+			minNode := nodeSamples[0].Value
+			for _, sample := range nodeSamples {
+				if sample.Value < minNode {
+					minNode = sample.Value
+				}
+			}
+			utilisation[node] = minNode
+			busy = busy && minNode >= minUtil
+		}
+		testutils.Printf("Info", "GPU utilisation per node (lowest GPU): %v", utilisation)
+		return busy
+	}, maxRetries, 30*time.Second)
+	if err != nil {
+		return utilisation, fmt.Errorf("GPU utilisation did not reach %v%% on all nodes: %v", minUtil, err)
+	}
+	return utilisation, nil
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+	"ci-tools-nvidia-gpu-operator/testutils/inventory"
+)
+
+var _ = Describe("test_gfd_labels :", Ordered, func() {
+	var (
+		config   *rest.Config
+		gfdNodes []inventory.NodeGpus
+	)
+
+	BeforeAll(func() {
+		config = internal.GetClientConfig()
+	})
+
+	It("get GPU nodes labeled by GPU Feature Discovery", func() {
+		inv, err := testutils.GetGpuInventory(config)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveGpuInventory(inv)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inv.NfdNodes()).ToNot(BeEmpty(), "NFD found no NVIDIA PCI device")
+		gfdNodes = inv.GfdNodes()
+		Expect(gfdNodes).ToNot(BeEmpty(), "No node labeled by GPU Feature Discovery")
+		Expect(len(gfdNodes)).To(Equal(len(inv.NfdNodes())), "GPU Feature Discovery did not label all the NFD GPU nodes")
+	})
+
+	It("GFD labels should match the GPU capacity and NFD PCI devices", func() {
+		inconsistencies := []string{}
+		for _, n := range gfdNodes {
+			for _, e := range inventory.CheckGfdLabels(n, nil) {
+				inconsistencies = append(inconsistencies, fmt.Sprintf("%v: %v", n.Node, e))
+			}
+		}
+		Expect(inconsistencies).To(BeEmpty())
+	})
+
+	It("GFD labels should match nvidia-smi", func() {
+		// nvidia-smi of the driver container sees all the GPUs of the node,
+		// whatever is allocated or shared
+		pods, err := ocputils.GetPodsByLabel(config, "", ocputils.DriverPodLabel)
+		Expect(err).ToNot(HaveOccurred())
+		driverPods := map[string]corev1.Pod{}
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == corev1.PodRunning {
+				driverPods[pod.Spec.NodeName] = pod
+			}
+		}
+		if len(driverPods) == 0 {
+			Skip(fmt.Sprintf("No running driver pod with label %v, the driver is not managed by the GPU operator", ocputils.DriverPodLabel))
+		}
+
+		inconsistencies := []string{}
+		for _, n := range gfdNodes {
+			pod, ok := driverPods[n.Node]
+			if !ok {
+				inconsistencies = append(inconsistencies, fmt.Sprintf("%v: no running driver pod", n.Node))
+				continue
+			}
+			command := []string{"bash", "-c", inventory.SmiQueryScript}
+			stdout, stderr, err := ocputils.ExecInPod(config, pod, ocputils.DriverContainerName, command)
+			_ = testutils.SaveToArtifactsDir(stdout, fmt.Sprintf("nvidia_smi-%v.log", n.Node))
+			Expect(err).ToNot(HaveOccurred(), "nvidia-smi failed in %v on %v:\n%s", pod.Name, n.Node, stderr)
+			smi, err := inventory.ParseSmiOutput(string(stdout))
+			Expect(err).ToNot(HaveOccurred(), "Invalid nvidia-smi output on %v:\n%s", n.Node, stdout)
+			_ = testutils.SaveAsJsonToArtifactsDir(smi, fmt.Sprintf("nvidia_smi-%v.json", n.Node))
+			for _, e := range inventory.CheckGfdLabels(n, smi) {
+				inconsistencies = append(inconsistencies, fmt.Sprintf("%v: %v", n.Node, e))
+			}
+		}
+		Expect(inconsistencies).To(BeEmpty())
+	})
+})
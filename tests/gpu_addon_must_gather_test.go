@@ -1,8 +1,6 @@
 package tests
 
 import (
-	"fmt"
-	"os/exec"
 	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -17,16 +15,15 @@ import (
 
 var _ = Describe("gpu_addon_must_gather :", Ordered, func() {
 	var (
-		config          *rest.Config
-		gpuAddonCsv     *operatorsv1alpha1.ClusterServiceVersion
-		mustGatherImage string
+		config      *rest.Config
+		gpuAddonCsv *operatorsv1alpha1.ClusterServiceVersion
 	)
 
 	BeforeAll(func() {
 		config = internal.GetClientConfig()
 
 	})
-	It("fetch must gather image from csv", func() {
+	It("find GPU add-on CSV", func() {
 		csvs, err := ocputils.GetCsvsByLabel(config, "", "")
 		Expect(err).ToNot(HaveOccurred())
 		Expect(csvs.Items).ToNot(BeEmpty())
@@ -40,31 +37,10 @@ var _ = Describe("gpu_addon_must_gather :", Ordered, func() {
 		err = testutils.SaveAsJsonToArtifactsDir(gpuAddonCsv, "gpu-addon-csv.json")
 		Expect(err).ToNot(HaveOccurred())
 		testutils.Printf("Info", "GPU add-on name=%v namespace=%v version=%v", gpuAddonCsv.Name, gpuAddonCsv.Namespace, gpuAddonCsv.Spec.Version.String())
-		for _, image := range gpuAddonCsv.Spec.RelatedImages {
-			if image.Name == "must-gather" {
-				mustGatherImage = image.Image
-				break
-			}
-		}
-		Expect(mustGatherImage).ToNot(BeEmpty(), "must-gather image not found")
-		testutils.Printf("Info", "must-gather image: %s", mustGatherImage)
 	})
 
-	It("run addon must-gather", func() {
-		Expect(mustGatherImage).ToNot(BeEmpty(), "must-gather image not found")
-		cmd := fmt.Sprintf("adm must-gather --image=%s --dest-dir=%s", mustGatherImage, internal.Config.ArtifactDir)
-		out, err := runOcCommand(cmd)
-		Expect(err).ToNot(HaveOccurred())
-		err = testutils.SaveToArtifactsDir([]byte(out), "oc-must-gather-output.txt")
-		Expect(err).ToNot(HaveOccurred())
+	It("run GPU operator must-gather", func() {
+		Expect(gpuAddonCsv).ToNot(BeNil(), "CSV not found")
+		runMustGather(config, gpuAddonCsv.Namespace)
 	})
 })
-
-func runOcCommand(command string) (string, error) {
-	cmd := exec.Command("oc", strings.Split(command, " ")...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("%s", out)
-	}
-	return string(out), nil
-}
@@ -0,0 +1,431 @@
+package tests
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/tests/workloads"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+const (
+	// autoscalingAccelerator is the AcceleratorLabel of the autoscaling
+	// MachineSet nodes, only they get the autoscaling workload
+	autoscalingAccelerator = "nvidia-gpu-autoscaling-test"
+	// MachineSet names are label values of their machines
+	maxMachineSetNameSize = 63
+	// Set on the MachineSet by the MachineAutoscaler
+	autoscalerMinSizeAnnotation = "machine.openshift.io/cluster-api-autoscaler-node-group-min-size"
+)
+
+// gpuMachineSetCount returns the GPU count of the instance type of the
+// MachineSet, 0 until the Machine API sets the capacity annotations.
+func gpuMachineSetCount(ms *machinev1beta1.MachineSet) int64 {
+	count, err := strconv.ParseInt(ms.Annotations[ocputils.MachineSetGpuAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// newAutoscalingMachineSet returns a MachineSet of the instance type of
+// gpuMachineSet without replicas, its nodes are labeled with accelerator.
+// The GPU MachineSet shared with the other suites is left as it is. It is
+// labeled as the MachineSets of scale_gpu_nodes, delete_gpu_nodes deletes it
+// when the suite could not.
+func newAutoscalingMachineSet(gpuMachineSet *machinev1beta1.MachineSet, accelerator string) *machinev1beta1.MachineSet {
+	suffix := "-autoscaling"
+	name := gpuMachineSet.Name
+	if len(name)+len(suffix) > maxMachineSetNameSize {
+		name = strings.TrimRight(name[:maxMachineSetNameSize-len(suffix)], "-")
+	}
+	name += suffix
+
+	replicas := int32(0)
+	ms := &machinev1beta1.MachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   gpuMachineSet.Namespace,
+			Labels:      map[string]string{ocputils.CiMachineSetLabel: ocputils.CiMachineSetLabelValue},
+			Annotations: map[string]string{},
+		},
+		Spec: *gpuMachineSet.Spec.DeepCopy(),
+	}
+	// Same instance type, the autoscaler scales from zero with its capacity
+	for _, annotation := range ocputils.MachineSetCapacityAnnotations {
+		if value, ok := gpuMachineSet.Annotations[annotation]; ok {
+			ms.Annotations[annotation] = value
+		}
+	}
+	ms.Spec.Replicas = &replicas
+	if ms.Spec.Selector.MatchLabels == nil {
+		ms.Spec.Selector.MatchLabels = map[string]string{}
+	}
+	ms.Spec.Selector.MatchLabels[ocputils.MachineSetLabel] = name
+	if ms.Spec.Template.ObjectMeta.Labels == nil {
+		ms.Spec.Template.ObjectMeta.Labels = map[string]string{}
+	}
+	ms.Spec.Template.ObjectMeta.Labels[ocputils.MachineSetLabel] = name
+	if ms.Spec.Template.Spec.ObjectMeta.Labels == nil {
+		ms.Spec.Template.Spec.ObjectMeta.Labels = map[string]string{}
+	}
+	ms.Spec.Template.Spec.ObjectMeta.Labels[ocputils.AcceleratorLabel] = accelerator
+	return ms
+}
+
+// isAutoscalingMachineSet returns whether ms was created by
+// newAutoscalingMachineSet.
+func isAutoscalingMachineSet(ms *machinev1beta1.MachineSet) bool {
+	return ms.Spec.Template.Spec.ObjectMeta.Labels[ocputils.AcceleratorLabel] == autoscalingAccelerator
+}
+
+// readyPodsOnNode returns the pods of labelSelector running on node, and when
+// the last of them became Ready.
+func readyPodsOnNode(config *rest.Config, labelSelector string, node string) ([]corev1.Pod, time.Time, bool) {
+	pods, err := ocputils.GetPodsByLabel(config, "", labelSelector)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	onNode := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == node {
+			onNode = append(onNode, pod)
+		}
+	}
+	readyTime, ok := podsReadyTime(onNode)
+	return onNode, readyTime, ok
+}
+
+var _ = Describe("test_gpu_autoscaling :", Ordered, func() {
+	var (
+		config                   *rest.Config
+		namespace                string
+		gpuMachineSet            *machinev1beta1.MachineSet
+		autoscalingMachineSet    *machinev1beta1.MachineSet
+		accelerator              string
+		createdClusterAutoscaler bool
+		createdMachineAutoscaler bool
+		scaleDownEnabled         bool
+		probe                    workloads.Workload
+		pod                      *corev1.Pod
+		machine                  *machinev1beta1.Machine
+		node                     *corev1.Node
+		milestones               *milestoneRecorder
+	)
+
+	BeforeAll(func() {
+		namespace = internal.GpuAutoscalingNamespace
+		testutils.AddDiagnosticsNamespace(namespace)
+
+		accelerator = autoscalingAccelerator
+
+		config = internal.GetClientConfig()
+	})
+
+	// Everything created by the suite is removed even when a spec fails, the
+	// GPU MachineSet is never changed
+	AfterAll(func() {
+		err := ocputils.DeleteNamespace(config, namespace)
+		if err == nil {
+			err = testutils.ExecWithRetryBackoff("Wait until namespace is deleted", func() bool {
+				_, err := ocputils.GetNamespace(config, namespace)
+				return errors.IsNotFound(err)
+			}, 60, 10*time.Second)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			testutils.Printf("Error", "Failed to delete namespace %v: %v", namespace, err)
+		}
+		if createdMachineAutoscaler {
+			err := ocputils.DeleteMachineAutoscaler(config, internal.MachineApiNamespace, autoscalingMachineSet.Name)
+			if err != nil && !errors.IsNotFound(err) {
+				testutils.Printf("Error", "Failed to delete MachineAutoscaler %v: %v", autoscalingMachineSet.Name, err)
+			}
+		}
+		if createdClusterAutoscaler {
+			err := ocputils.DeleteClusterAutoscaler(config, ocputils.ClusterAutoscalerName)
+			if err != nil && !errors.IsNotFound(err) {
+				testutils.Printf("Error", "Failed to delete ClusterAutoscaler: %v", err)
+			}
+		}
+		if autoscalingMachineSet != nil {
+			// Scaled to zero first, the machines go with their MachineSet
+			// anyway but their deletion is then not awaited
+			ms, err := ocputils.GetMachineSet(config, internal.MachineApiNamespace, autoscalingMachineSet.Name)
+			if err == nil {
+				_, err = ocputils.ReconcileMachineSetReplicas(config, ms, 0)
+			}
+			if err == nil {
+				err = ocputils.DeleteMachineSet(config, internal.MachineApiNamespace, autoscalingMachineSet.Name)
+			}
+			if err != nil && !errors.IsNotFound(err) {
+				testutils.Printf("Error", "Failed to delete MachineSet %v: %v", autoscalingMachineSet.Name, err)
+			}
+		}
+	})
+
+	It("find the GPU MachineSet", func() {
+		workerMs, err := ocputils.GetWorkerMachineSets(config, internal.MachineApiNamespace)
+		Expect(err).ToNot(HaveOccurred())
+		// Prefer the MachineSet created by scale_gpu_nodes
+		for i, ms := range workerMs.Items {
+			if isAutoscalingMachineSet(&ms) {
+				// Left by a previous run
+				continue
+			}
+			if ms.Labels[ocputils.CiMachineSetLabel] == ocputils.CiMachineSetLabelValue {
+				gpuMachineSet = workerMs.Items[i].DeepCopy()
+				break
+			}
+			if gpuMachineSet == nil && gpuMachineSetCount(&ms) > 0 {
+				gpuMachineSet = workerMs.Items[i].DeepCopy()
+			}
+		}
+		Expect(gpuMachineSet).ToNot(BeNil(), "No GPU MachineSet found, run scale_gpu_nodes")
+		testutils.Printf("Info", "Autoscaling the instance type of MachineSet %v", gpuMachineSet.Name)
+
+		// The autoscaler needs the GPU count of the instance type to scale from zero
+		err = testutils.ExecWithRetryBackoff("Wait for the MachineSet GPU annotation", func() bool {
+			ms, err := ocputils.GetMachineSet(config, internal.MachineApiNamespace, gpuMachineSet.Name)
+			if err != nil {
+				return false
+			}
+			gpuMachineSet = ms
+			return gpuMachineSetCount(ms) > 0
+		}, 10, 15*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(gpuMachineSet, fmt.Sprintf("autoscaling_machineset-%v.json", gpuMachineSet.Name))
+		Expect(err).ToNot(HaveOccurred(), "MachineSet %v has no %v annotation, it cannot scale from zero", gpuMachineSet.Name, ocputils.MachineSetGpuAnnotation)
+	})
+
+	It("create the autoscaling GPU MachineSet", func() {
+		// The workload would be scheduled on the nodes of another MachineSet
+		nodes, err := ocputils.GetNodesByLabel(config, fmt.Sprintf("%v=%v", ocputils.AcceleratorLabel, accelerator))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodes.Items).To(BeEmpty(), "Nodes of other MachineSets are labeled %v=%v", ocputils.AcceleratorLabel, accelerator)
+
+		ms, err := ocputils.CreateMachineSet(config, internal.MachineApiNamespace, newAutoscalingMachineSet(gpuMachineSet, accelerator))
+		Expect(err).ToNot(HaveOccurred())
+		autoscalingMachineSet = ms
+		testutils.Printf("Info", "Created MachineSet %v without replicas", autoscalingMachineSet.Name)
+		_ = testutils.SaveAsJsonToArtifactsDir(autoscalingMachineSet, fmt.Sprintf("autoscaling_machineset-%v.json", autoscalingMachineSet.Name))
+	})
+
+	It("create the ClusterAutoscaler", func() {
+		ca, err := ocputils.GetClusterAutoscaler(config, ocputils.ClusterAutoscalerName)
+		if err == nil {
+			// The cluster autoscaler is not ours to change
+			scaleDownEnabled = ca.Spec.ScaleDown != nil && ca.Spec.ScaleDown.Enabled
+			testutils.Printf("Info", "Using the existing ClusterAutoscaler, scale down enabled: %v", scaleDownEnabled)
+			_ = testutils.SaveAsJsonToArtifactsDir(ca, "cluster_autoscaler.json")
+			return
+		}
+		Expect(errors.IsNotFound(err)).To(BeTrue(), "Failed to get the ClusterAutoscaler: %v", err)
+		ca = &ocputils.ClusterAutoscaler{
+			Spec: ocputils.ClusterAutoscalerSpec{
+				ResourceLimits: &ocputils.ResourceLimits{
+					GPUs: []ocputils.GPULimit{
+						{Type: accelerator, Min: 0, Max: 16},
+					},
+				},
+				ScaleDown: &ocputils.ScaleDown{
+					Enabled:          true,
+					DelayAfterAdd:    "2m",
+					DelayAfterDelete: "1m",
+					UnneededTime:     "2m",
+				},
+			},
+		}
+		ca.Name = ocputils.ClusterAutoscalerName
+		ca, err = ocputils.CreateClusterAutoscaler(config, ca)
+		Expect(err).ToNot(HaveOccurred())
+		createdClusterAutoscaler = true
+		scaleDownEnabled = true
+		err = testutils.SaveAsJsonToArtifactsDir(ca, "cluster_autoscaler.json")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("create the MachineAutoscaler", func() {
+		ma := ocputils.NewMachineAutoscaler(internal.MachineApiNamespace, autoscalingMachineSet.Name, 0, 1)
+		ma, err := ocputils.EnsureMachineAutoscaler(config, ma)
+		Expect(err).ToNot(HaveOccurred())
+		createdMachineAutoscaler = true
+		_ = testutils.SaveAsJsonToArtifactsDir(ma, "machine_autoscaler.json")
+
+		err = testutils.ExecWithRetryBackoff("Wait for the MachineAutoscaler to target the MachineSet", func() bool {
+			ms, err := ocputils.GetMachineSet(config, internal.MachineApiNamespace, autoscalingMachineSet.Name)
+			if err != nil {
+				return false
+			}
+			autoscalingMachineSet = ms
+			_, ok := ms.Annotations[autoscalerMinSizeAnnotation]
+			return ok
+		}, 10, 15*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("create autoscaling test namespace", func() {
+		ns, err := ocputils.CreateNamespace(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveAsJsonToArtifactsDir(ns, "gpu_autoscaling_namespace.json")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("submit a pending GPU workload", func() {
+		probe = &probeWorkload{name: "gpu-autoscaling"}
+		_, err := ocputils.CreateConfigMap(config, workloads.NewConfigMap(namespace, probe))
+		Expect(err).ToNot(HaveOccurred())
+		pod = newProbePod(namespace, probe, 1)
+		// The node of the MachineSet template has none of the labels set
+		// once the node joins, e.g. by GFD
+		pod.Spec.NodeSelector = map[string]string{
+			ocputils.AcceleratorLabel: accelerator,
+		}
+		pod, err = ocputils.CreatePod(config, pod)
+		Expect(err).ToNot(HaveOccurred())
+		milestones, err = newMilestoneRecorder(pod.CreationTimestamp.Time, autoscalingMilestones)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("wait for a new GPU machine", func() {
+		selector := fmt.Sprintf("%v=%v", ocputils.MachineSetLabel, autoscalingMachineSet.Name)
+		err := testutils.ExecWithRetryBackoff("Wait for the autoscaler to create a machine", func() bool {
+			machines, err := ocputils.GetMachinesByLabel(config, internal.MachineApiNamespace, selector)
+			if err != nil || len(machines.Items) == 0 {
+				return false
+			}
+			machine = &machines.Items[0]
+			return true
+		}, 30, 30*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "The autoscaler did not scale up MachineSet %v", autoscalingMachineSet.Name)
+		milestones.record(milestoneAutoscalingMachineCreated, machine.CreationTimestamp.Time, "Machine creation")
+	})
+
+	It("wait for the new GPU node to be Ready", func() {
+		var machineError string
+		err := testutils.ExecWithRetryBackoff("Wait for the GPU node to be Ready", func() bool {
+			m, err := ocputils.GetMachine(config, internal.MachineApiNamespace, machine.Name)
+			if err != nil {
+				return false
+			}
+			machine = m
+			if failures := ocputils.MachineFailures([]machinev1beta1.Machine{*machine}); len(failures) > 0 {
+				machineError = failures[0].String()
+				return true
+			}
+			if machine.Status.NodeRef == nil {
+				return false
+			}
+			n, err := ocputils.GetNode(config, machine.Status.NodeRef.Name)
+			if err != nil {
+				return false
+			}
+			node = n
+			for _, condition := range node.Status.Conditions {
+				if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+					milestones.record(milestoneAutoscalingNodeReady, condition.LastTransitionTime.Time, "Node condition")
+					return true
+				}
+			}
+			return false
+		}, 40, 30*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(machine, fmt.Sprintf("autoscaling_machine-%v.json", machine.Name))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machineError).To(BeEmpty(), "Machine failed")
+	})
+
+	It("GPU operator should initialise the new GPU node", func() {
+		err := testutils.ExecWithRetryBackoff("Wait for the GPU operator on the new node", func() bool {
+			// Without driver pods the driver is pre-installed on the node
+			driverPods, readyTime, ready := readyPodsOnNode(config, ocputils.DriverPodLabel, node.Name)
+			if len(driverPods) > 0 && !ready {
+				return false
+			}
+			if ready {
+				milestones.record(milestoneAutoscalingDriverReady, readyTime, "pod condition")
+			}
+			_, readyTime, ready = readyPodsOnNode(config, devicePluginPodLabel, node.Name)
+			if !ready {
+				return false
+			}
+			milestones.record(milestoneAutoscalingDevicePluginReady, readyTime, "pod condition")
+			n, err := ocputils.GetNode(config, node.Name)
+			if err != nil {
+				return false
+			}
+			node = n
+			capacity := node.Status.Capacity[workloads.GpuResource]
+			if capacity.Value() == 0 {
+				return false
+			}
+			milestones.record(milestoneAutoscalingGpuCapacity, time.Now(), "observed")
+			return true
+		}, 60, 30*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(node, fmt.Sprintf("autoscaling_node-%v.json", node.Name))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("GPU workload should run on the new GPU node", func() {
+		err := testutils.ExecWithRetryBackoff("Wait for the GPU workload to finish", func() bool {
+			p, err := ocputils.GetPod(config, namespace, pod.Name)
+			if err != nil {
+				return false
+			}
+			pod = p
+			return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+		}, 20, 15*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(pod, fmt.Sprintf("pod_%v.json", pod.Name))
+		Expect(err).ToNot(HaveOccurred())
+		if pod.Status.StartTime != nil {
+			milestones.record(milestoneAutoscalingWorkloadStarted, pod.Status.StartTime.Time, "pod start time")
+		}
+		Expect(pod.Spec.NodeName).To(Equal(node.Name), "GPU workload did not run on the new node")
+		output, err := ocputils.GetPodLogs(config, *pod, false)
+		Expect(err).ToNot(HaveOccurred())
+		_ = testutils.SaveToArtifactsDir([]byte(*output), fmt.Sprintf("pod_%v_output.log", pod.Name))
+		Expect(pod.Status.Phase).To(Equal(corev1.PodSucceeded), "GPU workload failed:\n%v", *output)
+		gpus, err := probeGpuCount(*output)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gpus).To(BeNumerically(">", 0), "GPU workload sees no GPU:\n%v", *output)
+	})
+
+	It("save autoscaling milestones", func() {
+		err := milestones.save()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("autoscaling milestones should be within budget", func() {
+		if len(milestones.budgets) == 0 {
+			Skip("No autoscaling milestone budget, set MILESTONE_BUDGETS")
+		}
+		Expect(milestones.exceeded()).To(BeEmpty(), "Autoscaling milestones over budget")
+	})
+
+	It("GPU node should be scaled down once the workload completed", func() {
+		if !scaleDownEnabled {
+			Skip("Scale down is disabled in the existing ClusterAutoscaler")
+		}
+		finished := time.Now()
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				finished = status.State.Terminated.FinishedAt.Time
+			}
+		}
+		err := testutils.ExecWithRetryBackoff("Wait for the autoscaler to remove the GPU node", func() bool {
+			_, err := ocputils.GetNode(config, node.Name)
+			return errors.IsNotFound(err)
+		}, 40, 30*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "The autoscaler did not scale down MachineSet %v", autoscalingMachineSet.Name)
+		testutils.Printf("Info", "GPU node %v removed %v after the workload completed", node.Name, time.Since(finished).Round(time.Second))
+	})
+})
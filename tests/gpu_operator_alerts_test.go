@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+const (
+	driverFailedAlert  = "GPUOperatorNodeDeploymentDriverFailed"
+	invalidDriverImage = "invalid-driver-image"
+)
+
+// findAlertingRule returns the loaded alerting rule named alert.
+func findAlertingRule(groups []ocputils.PrometheusRuleGroup, alert string) *ocputils.PrometheusRuleState {
+	for _, group := range groups {
+		for i, rule := range group.Rules {
+			if rule.Type == "alerting" && rule.Name == alert {
+				return &group.Rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+func isAlertFiring(rule *ocputils.PrometheusRuleState) bool {
+	for _, alert := range rule.Alerts {
+		if alert.State == "firing" {
+			return true
+		}
+	}
+	return false
+}
+
+var _ = Describe("test_gpu_operator_alerts :", Ordered, func() {
+	var (
+		config        *rest.Config
+		prom          *ocputils.PrometheusClient
+		namespace     string
+		rules         []ocputils.RuleSpec
+		clusterPolicy *gpuv1.ClusterPolicy
+	)
+
+	BeforeAll(func() {
+		config = internal.GetClientConfig()
+	})
+
+	It("capture GPU Operator namespace", func() {
+		csvs, err := ocputils.GetCsvsByLabel(config, "", "")
+		Expect(err).ToNot(HaveOccurred())
+		for _, csv := range csvs.Items {
+			if strings.Contains(csv.Name, "gpu-operator-certified") {
+				namespace = csv.Namespace
+				break
+			}
+		}
+		Expect(namespace).ToNot(BeEmpty(), "CSV not found")
+		testutils.AddDiagnosticsNamespace(namespace)
+	})
+
+	It("create Prometheus client", func() {
+		var err error
+		prom, err = ocputils.NewThanosQuerierClient(config)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("GPU Operator namespace should have PrometheusRules", func() {
+		promRules, err := ocputils.GetPrometheusRules(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveAsJsonToArtifactsDir(promRules, "gpu-operator-prometheusrules.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(promRules).ToNot(BeEmpty(), "No PrometheusRule in namespace %v", namespace)
+		for _, promRule := range promRules {
+			for _, group := range promRule.Spec.Groups {
+				rules = append(rules, group.Rules...)
+			}
+		}
+		Expect(rules).ToNot(BeEmpty(), "PrometheusRules have no rules")
+	})
+
+	It("rule expressions should be valid PromQL", func() {
+		invalid := []string{}
+		for _, rule := range rules {
+			err := prom.ValidateExpr(rule.Expr.String())
+			promErr := &ocputils.PrometheusError{}
+			if errors.As(err, &promErr) && promErr.Type == "bad_data" {
+				invalid = append(invalid, fmt.Sprintf("%v%v: %v", rule.Alert, rule.Record, promErr.Message))
+				continue
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(invalid).To(BeEmpty(), "Invalid PromQL expressions")
+	})
+
+	It("rules should be loaded by Prometheus", func() {
+		missing := []string{}
+		unhealthy := []string{}
+		err := testutils.ExecWithRetryBackoff("Rules loaded by Prometheus", func() bool {
+			groups, err := prom.Rules()
+			if err != nil {
+				testutils.Printf("Error", "%v", err)
+				return false
+			}
+			_ = testutils.SaveAsJsonToArtifactsDir(groups, "prometheus-rules.json")
+			missing = []string{}
+			unhealthy = []string{}
+			for _, rule := range rules {
+				if len(rule.Alert) == 0 {
+					continue
+				}
+				loaded := findAlertingRule(groups, rule.Alert)
+				if loaded == nil {
+					missing = append(missing, rule.Alert)
+					continue
+				}
+				if loaded.Health == "err" {
+					unhealthy = append(unhealthy, fmt.Sprintf("%v: %v", rule.Alert, loaded.LastError))
+				}
+			}
+			return len(missing) == 0 && len(unhealthy) == 0
+		}, 10, 30*time.Second)
+		Expect(unhealthy).To(BeEmpty(), "Rule evaluation fails")
+		Expect(err).ToNot(HaveOccurred(), "Alerts not loaded by Prometheus: %v", missing)
+	})
+
+	Context("induced driver failure", Ordered, func() {
+		var (
+			driverPatched bool
+			alertFor      time.Duration
+		)
+
+		// restoreDriver reverts the driver image fields to the saved ClusterPolicy
+		restoreDriver := func() error {
+			driver := map[string]interface{}{
+				"repository": nil,
+				"image":      nil,
+				"version":    nil,
+			}
+			if len(clusterPolicy.Spec.Driver.Repository) > 0 {
+				driver["repository"] = clusterPolicy.Spec.Driver.Repository
+			}
+			if len(clusterPolicy.Spec.Driver.Image) > 0 {
+				driver["image"] = clusterPolicy.Spec.Driver.Image
+			}
+			if len(clusterPolicy.Spec.Driver.Version) > 0 {
+				driver["version"] = clusterPolicy.Spec.Driver.Version
+			}
+			patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"driver": driver}})
+			if err != nil {
+				return err
+			}
+			_, err = ocputils.PatchClusterPolicy(config, clusterPolicy.Name, patch, types.MergePatchType)
+			if err == nil {
+				driverPatched = false
+			}
+			return err
+		}
+
+		// waitForAlert waits until the driver failure alert is firing or resolved
+		waitForAlert := func(firing bool) {
+			state := "resolved"
+			if firing {
+				state = "firing"
+			}
+			// Alerts only fire after being pending for their "for" duration
+			maxRetries := int((alertFor + 15*time.Minute) / (30 * time.Second))
+			err := testutils.ExecWithRetryBackoff(fmt.Sprintf("Wait for %v to be %v", driverFailedAlert, state), func() bool {
+				groups, err := prom.Rules()
+				if err != nil {
+					testutils.Printf("Error", "%v", err)
+					return false
+				}
+				rule := findAlertingRule(groups, driverFailedAlert)
+				if rule == nil {
+					return false
+				}
+				_ = testutils.SaveAsJsonToArtifactsDir(rule, fmt.Sprintf("alert-%v-%v.json", driverFailedAlert, state))
+				return isAlertFiring(rule) == firing
+			}, maxRetries, 30*time.Second)
+			Expect(err).ToNot(HaveOccurred(), "%v is not %v", driverFailedAlert, state)
+		}
+
+		BeforeAll(func() {
+			for _, rule := range rules {
+				if rule.Alert != driverFailedAlert {
+					continue
+				}
+				if len(rule.For) > 0 {
+					var err error
+					alertFor, err = time.ParseDuration(rule.For)
+					Expect(err).ToNot(HaveOccurred(), "Invalid 'for' duration of %v", driverFailedAlert)
+				}
+				return
+			}
+			Skip(fmt.Sprintf("The GPU Operator does not ship the %v alert", driverFailedAlert))
+		})
+
+		AfterAll(func() {
+			if driverPatched {
+				err := restoreDriver()
+				Expect(err).ToNot(HaveOccurred(), "Failed to restore the ClusterPolicy driver image")
+			}
+		})
+
+		It("save the ClusterPolicy", func() {
+			var err error
+			clusterPolicy, err = ocputils.GetClusterPolicy(config)
+			Expect(err).ToNot(HaveOccurred())
+			err = testutils.SaveAsJsonToArtifactsDir(clusterPolicy, "clusterpolicy-before-driver-failure.json")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It(fmt.Sprintf("%v should not be firing", driverFailedAlert), func() {
+			waitForAlert(false)
+		})
+
+		It("set an invalid driver image in the ClusterPolicy", func() {
+			patch := fmt.Sprintf("{\"spec\": {\"driver\": {\"image\": \"%v\"}}}", invalidDriverImage)
+			driverPatched = true
+			cp, err := ocputils.PatchClusterPolicy(config, clusterPolicy.Name, []byte(patch), types.MergePatchType)
+			Expect(err).ToNot(HaveOccurred())
+			err = testutils.SaveAsJsonToArtifactsDir(cp, "clusterpolicy-driver-failure.json")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It(fmt.Sprintf("%v should fire", driverFailedAlert), func() {
+			waitForAlert(true)
+		})
+
+		It("restore the ClusterPolicy driver image", func() {
+			err := restoreDriver()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It(fmt.Sprintf("%v should resolve", driverFailedAlert), func() {
+			waitForAlert(false)
+		})
+	})
+})
@@ -3,6 +3,7 @@ package tests
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"ci-tools-nvidia-gpu-operator/internal"
 	"ci-tools-nvidia-gpu-operator/ocputils"
 	"ci-tools-nvidia-gpu-operator/testutils"
+	"ci-tools-nvidia-gpu-operator/testutils/metrics"
 )
 
 const (
@@ -25,6 +27,8 @@ const (
 	nodeStatusPort    = "8000"
 	gpuOpMetricsPort  = "8080"
 	monitoringLabel   = "openshift.io/cluster-monitoring"
+	// LeaderElectionID of the gpu-operator manager
+	gpuOpLeaderLease = "53822513.nvidia.com"
 )
 
 var _ = Describe("test_gpu_operator_metrics :", Ordered, func() {
@@ -54,6 +58,7 @@ var _ = Describe("test_gpu_operator_metrics :", Ordered, func() {
 		}
 		Expect(gpuOperatorCsv).ToNot(BeNil(), "CSV not found")
 		Expect(namespace).ToNot(BeEmpty())
+		testutils.AddDiagnosticsNamespace(namespace)
 		Expect(gpuOpVersion).ToNot(BeNil())
 		err = testutils.SaveAsJsonToArtifactsDir(gpuOperatorCsv, "gpu-operator-csv.json")
 		Expect(err).ToNot(HaveOccurred())
@@ -95,7 +100,10 @@ var _ = Describe("test_gpu_operator_metrics :", Ordered, func() {
 	})
 
 	Context("DCGM metrics", func() {
-		var dcgmPods []corev1.Pod
+		var (
+			dcgmPods  []corev1.Pod
+			smTargets *serviceMonitorTargets
+		)
 
 		It("validate that the DCGM metrics are correctly exposed", func() {
 			pods, err := ocputils.GetPodsByLabel(config, namespace, "app=nvidia-dcgm-exporter")
@@ -151,112 +159,199 @@ var _ = Describe("test_gpu_operator_metrics :", Ordered, func() {
 			Expect(string(resp)).ToNot(BeEmpty(), "scrape pool is empty")
 			err = testutils.SaveToArtifactsDir(resp, "metrics-dcgm-exporter.txt")
 			Expect(err).ToNot(HaveOccurred())
+			families, err := metrics.Parse(string(resp))
+			Expect(err).ToNot(HaveOccurred(), "DCGM exporter metrics are not in the Prometheus text format")
+			for _, name := range metrics.ExpectedMetrics(metrics.DcgmExporter, gpuOpVersion.Version) {
+				Expect(families).To(metrics.HaveMetric(name))
+			}
+			for _, name := range metrics.OptionalMetrics(metrics.DcgmExporter, gpuOpVersion.Version) {
+				if _, ok := families[name]; !ok {
+					testutils.Printf("Info", "Optional DCGM metric %v is not exported", name)
+				}
+			}
+			Expect(families).To(metrics.MetricValueInRange("DCGM_FI_DEV_GPU_UTIL", nil, 0, 100))
+		})
+
+		It("check that the DCGM ServiceMonitor selects the DCGM service", func() {
+			var err error
+			smTargets, err = getServiceMonitorTargets(config, namespace, "nvidia-dcgm-exporter")
+			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("check that prometheus is picking up DCGM service monitor", func() {
-			serviceMonitor := fmt.Sprintf("job_name: serviceMonitor/%v/nvidia-dcgm-exporter", namespace)
-			err := testutils.ExecWithRetryBackoff("DCGM prometheus pickup", func() bool {
-				prometheusSecret, err := ocputils.GetSecret(config, "openshift-monitoring", "prometheus-k8s")
-				if err != nil {
-					testutils.Printf("Error", "%v", err)
-					return false
-				}
-				promyml, err := ocputils.GetSecretValue(prometheusSecret, "prometheus.yaml.gz", true)
-				if err != nil {
-					testutils.Printf("Error", "%v", err)
-					return false
-				}
-				_ = testutils.SaveToArtifactsDir([]byte(*promyml), "prometheus.yaml.gz.txt")
-				return strings.Contains(*promyml, serviceMonitor)
-			}, 30, 30*time.Second)
+			err := waitForServiceMonitorJobs(config, namespace, "nvidia-dcgm-exporter", smTargets)
 			Expect(err).ToNot(HaveOccurred(), "Prometheus is not picking up DCGM metrics")
 		})
+
+		It("DCGM metrics should have fresh samples for every GPU node", func() {
+			prom, err := ocputils.NewThanosQuerierClient(config)
+			Expect(err).ToNot(HaveOccurred())
+			gpuNodes, err := getGpuNodeNames(config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gpuNodes).ToNot(BeEmpty(), "No GPU nodes found")
+			sampleAges := map[string]map[string]float64{}
+			for _, metric := range metrics.ExpectedMetrics(metrics.DcgmExporter, gpuOpVersion.Version) {
+				ages := map[string]float64{}
+				err := testutils.ExecWithRetryBackoff(fmt.Sprintf("%v fresh samples", metric), func() bool {
+					samples, err := prom.Query(fmt.Sprintf("time() - timestamp(%v)", metric))
+					if err != nil {
+						testutils.Printf("Error", "%v", err)
+						return false
+					}
+					byNode, err := dcgmSamplesByNode(config, samples)
+					if err != nil {
+						return false
+					}
+					fresh := true
+					for _, node := range gpuNodes {
+						nodeSamples, ok := byNode[node]
+						if !ok {
+							testutils.Printf("Info", "no %v sample for node %v", metric, node)
+							fresh = false
+							continue
+						}
+						for _, sample := range nodeSamples {
+							ages[node] = sample.Value
+							fresh = fresh && sample.Value <= dcgmMaxSampleAge
+						}
+					}
+					return fresh
+				}, 10, 30*time.Second)
+				sampleAges[metric] = ages
+				Expect(err).ToNot(HaveOccurred(), "%v has no fresh samples for every GPU node: %v", metric, ages)
+			}
+			err = testutils.SaveAsJsonToArtifactsDir(sampleAges, "dcgm-metrics-sample-age.json")
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	Context("node metrics", func() {
-		var nodeStatusExpPod corev1.Pod
+		var (
+			nodeStatusExpPods []corev1.Pod
+			nodeScrapes       []podScrape
+			smTargets         *serviceMonitorTargets
+		)
 
-		It("wait for node-status-exporter to start running", func() {
-			err := testutils.ExecWithRetryBackoff("node-status-exporter status", func() bool {
+		It("wait for node-status-exporter to run on every GPU node", func() {
+			gpuNodes, err := getGpuNodeNames(config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gpuNodes).ToNot(BeEmpty(), "No GPU nodes found")
+			err = testutils.ExecWithRetryBackoff("node-status-exporter status", func() bool {
 				pods, err := ocputils.GetPodsByLabel(config, namespace, "app=nvidia-node-status-exporter")
 				if err != nil {
 					return false
 				}
-				// Test one pod only
-				if len(pods.Items) < 1 {
-					return false
+				running := map[string]bool{}
+				for _, pod := range pods.Items {
+					podRunning := pod.Status.Phase == corev1.PodRunning
+					if prev, ok := running[pod.Spec.NodeName]; ok {
+						podRunning = podRunning && prev
+					}
+					running[pod.Spec.NodeName] = podRunning
+				}
+				for _, node := range gpuNodes {
+					if !running[node] {
+						testutils.Printf("Info", "node-status-exporter is not running on node %v", node)
+						return false
+					}
 				}
-				nodeStatusExpPod = pods.Items[0]
-				filename := fmt.Sprintf("pod-%v.json", nodeStatusExpPod.Name)
-				_ = testutils.SaveAsJsonToArtifactsDir(nodeStatusExpPod, filename)
-				return nodeStatusExpPod.Status.Phase == corev1.PodRunning
-			}, 2, 30*time.Second)
+				nodeStatusExpPods = pods.Items
+				return true
+			}, 10, 30*time.Second)
+			_ = testutils.SaveAsJsonToArtifactsDir(nodeStatusExpPods, "pods-node-status-exporter.json")
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("fetch node-status-exporter metrics", func() {
-			resp, err := ocputils.PodProxyGet(config, nodeStatusExpPod, nodeStatusPort, "metrics", map[string]string{})
-			Expect(err).ToNot(HaveOccurred())
-			Expect(string(resp)).ToNot(BeEmpty(), "scrape pool is empty")
-			err = testutils.SaveToArtifactsDir(resp, "metrics-node-status-exporter.txt")
+			nodeScrapes = scrapePods(config, nodeStatusExpPods, nodeStatusPort)
+			for _, scrape := range nodeScrapes {
+				node := scrape.Pod.Spec.NodeName
+				_ = testutils.SaveToArtifactsDir(scrape.Body, fmt.Sprintf("metrics-node-status-exporter-%v.txt", node))
+				Expect(scrape.Err).ToNot(HaveOccurred(), "Failed to scrape node-status-exporter on node %v", node)
+				Expect(string(scrape.Body)).ToNot(BeEmpty(), "scrape pool of node %v is empty", node)
+				for _, name := range metrics.ExpectedMetrics(metrics.NodeStatusExporter, gpuOpVersion.Version) {
+					Expect(scrape.Families).To(metrics.HaveMetric(name), "node %v", node)
+				}
+			}
+		})
+
+		It("node-status-exporter metrics should match the node labels", func() {
+			rows := []nodeMetricsRow{}
+			failed := []string{}
+			for _, scrape := range nodeScrapes {
+				node, err := ocputils.GetNode(config, scrape.Pod.Spec.NodeName)
+				Expect(err).ToNot(HaveOccurred())
+				row := newNodeMetricsRow(node, scrape)
+				rows = append(rows, row)
+				for _, e := range row.Errors {
+					failed = append(failed, fmt.Sprintf("%v: %v", row.Node, e))
+				}
+			}
+			sort.Slice(rows, func(i, j int) bool { return rows[i].Node < rows[j].Node })
+			_ = testutils.SaveAsJsonToArtifactsDir(rows, "node-status-exporter-nodes.json")
+			table := nodeMetricsTable(rows)
+			_ = testutils.SaveToArtifactsDir(table, "node-status-exporter-nodes.txt")
+			testutils.Printf("Info", "node-status-exporter metrics per node:\n%v", string(table))
+			Expect(failed).To(BeEmpty(), "node-status-exporter metrics do not match the nodes")
+		})
+
+		It("check that the node-status-exporter ServiceMonitor selects the node-status-exporter service", func() {
+			var err error
+			smTargets, err = getServiceMonitorTargets(config, namespace, "nvidia-node-status-exporter")
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("check that prometheus is picking up node-status-exporter service monitor", func() {
-			serviceMonitor := fmt.Sprintf("job_name: serviceMonitor/%v/nvidia-node-status-exporter", namespace)
-			err := testutils.ExecWithRetryBackoff("node-status-exporter prometheus pickup", func() bool {
-				prometheusSecret, err := ocputils.GetSecret(config, "openshift-monitoring", "prometheus-k8s")
-				if err != nil {
-					testutils.Printf("Error", "%v", err)
-					return false
-				}
-				promyml, err := ocputils.GetSecretValue(prometheusSecret, "prometheus.yaml.gz", true)
-				if err != nil {
-					testutils.Printf("Error", "%v", err)
-					return false
-				}
-				_ = testutils.SaveToArtifactsDir([]byte(*promyml), "prometheus.yaml.gz.txt")
-				return strings.Contains(*promyml, serviceMonitor)
-			}, 30, 30*time.Second)
+			err := waitForServiceMonitorJobs(config, namespace, "nvidia-node-status-exporter", smTargets)
 			Expect(err).ToNot(HaveOccurred(), "Prometheus is not picking up node-status-monitor metrics")
 		})
 	})
 
 	Context("operator metrics", func() {
-		var gpuOpPod corev1.Pod
+		var (
+			gpuOpPods []corev1.Pod
+			smTargets *serviceMonitorTargets
+		)
 
-		It("get gpu-operator pod", func() {
+		It("get gpu-operator pods", func() {
 			pods, err := ocputils.GetPodsByLabel(config, namespace, "app=gpu-operator")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(pods.Items).NotTo(BeEmpty())
-			gpuOpPod = pods.Items[0]
-			_ = testutils.SaveAsJsonToArtifactsDir(gpuOpPod, "pod-gpu-operator.json")
+			gpuOpPods = pods.Items
+			_ = testutils.SaveAsJsonToArtifactsDir(gpuOpPods, "pods-gpu-operator.json")
 		})
 
 		It("fetch gpu-operator metrics", func() {
-			resp, err := ocputils.PodProxyGet(config, gpuOpPod, gpuOpMetricsPort, "metrics", map[string]string{})
+			// Only the leader reconciles and exposes the reconciliation metrics
+			leaderName, err := ocputils.GetLeaderPodName(config, namespace, gpuOpLeaderLease)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(string(resp)).ToNot(BeEmpty(), "scrape pool is empty")
-			err = testutils.SaveToArtifactsDir(resp, "metrics-gpu-operator.txt")
+			var leader *podScrape
+			scrapes := scrapePods(config, gpuOpPods, gpuOpMetricsPort)
+			for i, scrape := range scrapes {
+				_ = testutils.SaveToArtifactsDir(scrape.Body, fmt.Sprintf("metrics-gpu-operator-%v.txt", scrape.Pod.Name))
+				Expect(scrape.Err).ToNot(HaveOccurred(), "Failed to scrape gpu-operator pod %v", scrape.Pod.Name)
+				Expect(string(scrape.Body)).ToNot(BeEmpty(), "scrape pool of pod %v is empty", scrape.Pod.Name)
+				if scrape.Pod.Name == leaderName {
+					leader = &scrapes[i]
+				}
+			}
+			Expect(leader).ToNot(BeNil(), "leader %v of lease %v is not a gpu-operator pod", leaderName, gpuOpLeaderLease)
+			testutils.Printf("Info", "gpu-operator leader pod: %v", leader.Pod.Name)
+			for _, name := range metrics.ExpectedMetrics(metrics.GpuOperator, gpuOpVersion.Version) {
+				Expect(leader.Families).To(metrics.HaveMetric(name))
+			}
+			// The ClusterPolicy is ready after wait_for_gpu_operator
+			Expect(leader.Families).To(metrics.MetricValueInRange("gpu_operator_reconciliation_status", nil, 1, 1))
+		})
+
+		It("check that the gpu-operator ServiceMonitor selects the gpu-operator service", func() {
+			var err error
+			smTargets, err = getServiceMonitorTargets(config, namespace, "gpu-operator")
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("check that prometheus is picking up gpu-operator service monitor", func() {
-			serviceMonitor := fmt.Sprintf("job_name: serviceMonitor/%v/gpu-operator", namespace)
-			err := testutils.ExecWithRetryBackoff("gpu-operator prometheus pickup", func() bool {
-				prometheusSecret, err := ocputils.GetSecret(config, "openshift-monitoring", "prometheus-k8s")
-				if err != nil {
-					testutils.Printf("Error", "%v", err)
-					return false
-				}
-				promyml, err := ocputils.GetSecretValue(prometheusSecret, "prometheus.yaml.gz", true)
-				if err != nil {
-					testutils.Printf("Error", "%v", err)
-					return false
-				}
-				_ = testutils.SaveToArtifactsDir([]byte(*promyml), "prometheus.yaml.gz.txt")
-				return strings.Contains(*promyml, serviceMonitor)
-			}, 30, 30*time.Second)
+			err := waitForServiceMonitorJobs(config, namespace, "gpu-operator", smTargets)
 			Expect(err).ToNot(HaveOccurred(), "Prometheus is not picking up gpu-operator metrics")
 		})
 	})
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"path"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+// runMustGather collects the GPU operator must-gather of namespace into the
+// artifacts dir and checks the ClusterPolicy was collected.
+func runMustGather(config *rest.Config, namespace string) {
+	result, err := ocputils.MustGather(config, ocputils.MustGatherOptions{
+		Namespace: namespace,
+		DestDir:   internal.Config.ArtifactDir,
+		BugReport: true,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	err = testutils.SaveAsJsonToArtifactsDir(result, "must-gather-result.json")
+	Expect(err).ToNot(HaveOccurred())
+	testutils.Printf("Info", "must-gather tarball %v has %v files, %v errors", result.Tarball, len(result.Files), len(result.Errors))
+	for _, e := range result.Errors {
+		testutils.Printf("Warning", "must-gather: %v", e)
+	}
+	Expect(result.Files).To(ContainElement(path.Join("must-gather", "cluster_policy.yaml")))
+}
+
+var _ = Describe("gpu_operator_must_gather :", Ordered, func() {
+	var (
+		config    *rest.Config
+		namespace string
+	)
+
+	BeforeAll(func() {
+		config = internal.GetClientConfig()
+	})
+
+	It("find GPU operator namespace", func() {
+		// The operator pod is there whatever the install method
+		pods, err := ocputils.GetPodsByLabel(config, "", "app=gpu-operator")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pods.Items).ToNot(BeEmpty(), "gpu-operator pod not found")
+		namespace = pods.Items[0].Namespace
+		testutils.Printf("Info", "GPU operator namespace: %v", namespace)
+	})
+
+	It("run must-gather", func() {
+		runMustGather(config, namespace)
+	})
+})
@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/tests/workloads"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+const (
+	// The CUDA base image sets NVIDIA_VISIBLE_DEVICES=all
+	probeImage  = "nvcr.io/nvidia/cuda:12.2.0-base-ubi8"
+	probeScript = `#!/bin/bash
+nvidia-smi -L
+echo "GPU count: $(nvidia-smi -L 2>/dev/null | grep -c '^GPU')"`
+)
+
+// probeWorkload runs a single script, it is used to build pods from the
+// workloads pod template that are expected to not get a GPU.
+type probeWorkload struct {
+	name string
+}
+
+func (p *probeWorkload) Name() string {
+	return p.name
+}
+
+func (p *probeWorkload) Image() string {
+	return probeImage
+}
+
+func (p *probeWorkload) Entrypoint() string {
+	return probeScript
+}
+
+func (p *probeWorkload) Parse(logs string) workloads.Result {
+	return workloads.Result{
+		Workload: p.name,
+		Passed:   strings.Contains(logs, "GPU count: 0"),
+	}
+}
+
+// probeGpuCount returns the GPU count printed by probeScript.
+func probeGpuCount(logs string) (int, error) {
+	for _, line := range strings.Split(logs, "\n") {
+		if count, ok := strings.CutPrefix(strings.TrimSpace(line), "GPU count:"); ok {
+			return strconv.Atoi(strings.TrimSpace(count))
+		}
+	}
+	return 0, fmt.Errorf("no GPU count in the probe output:\n%v", logs)
+}
+
+func newProbePod(namespace string, w workloads.Workload, gpus int64) *corev1.Pod {
+	template := workloads.NewPodTemplate(w, gpus)
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      w.Name(),
+			Namespace: namespace,
+			Labels:    template.ObjectMeta.Labels,
+		},
+		Spec: template.Spec,
+	}
+}
+
+var _ = Describe("test_gpu_workload_scheduling :", Ordered, func() {
+	var (
+		config      *rest.Config
+		namespace   string
+		gpuNodes    []corev1.Node
+		maxCapacity int64
+	)
+
+	BeforeAll(func() {
+		namespace = internal.GpuSchedulingNamespace
+		testutils.AddDiagnosticsNamespace(namespace)
+
+		config = internal.GetClientConfig()
+	})
+
+	// waitForFailedScheduling waits for a FailedScheduling event of the pod
+	// containing reason and checks that the pod is still Pending.
+	waitForFailedScheduling := func(podName string, reason string) {
+		var message string
+		err := testutils.ExecWithRetryBackoff(fmt.Sprintf("Wait for FailedScheduling event of pod %v", podName), func() bool {
+			fieldSelector := fmt.Sprintf("involvedObject.kind=Pod,involvedObject.name=%v", podName)
+			events, err := ocputils.GetEventsByFieldSelector(config, namespace, fieldSelector)
+			if err != nil {
+				return false
+			}
+			_ = testutils.SaveAsJsonToArtifactsDir(events, fmt.Sprintf("events_pod_%v.json", podName))
+			for _, event := range events.Items {
+				if event.Reason == "FailedScheduling" && strings.Contains(event.Message, reason) {
+					message = event.Message
+					return true
+				}
+			}
+			return false
+		}, 20, 15*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "No FailedScheduling event with '%v' for pod %v", reason, podName)
+		testutils.Printf("Info", "pod %v: %v", podName, message)
+
+		pod, err := ocputils.GetPod(config, namespace, podName)
+		Expect(err).ToNot(HaveOccurred())
+		_ = testutils.SaveAsJsonToArtifactsDir(pod, fmt.Sprintf("pod_%v.json", podName))
+		Expect(pod.Status.Phase).To(Equal(corev1.PodPending))
+		Expect(pod.Spec.NodeName).To(BeEmpty(), "pod %v was scheduled on %v", podName, pod.Spec.NodeName)
+	}
+
+	createProbe := func(name string, gpus int64) (workloads.Workload, *corev1.Pod) {
+		probe := &probeWorkload{name: name}
+		_, err := ocputils.CreateConfigMap(config, workloads.NewConfigMap(namespace, probe))
+		Expect(err).ToNot(HaveOccurred())
+		return probe, newProbePod(namespace, probe, gpus)
+	}
+
+	It("get GPU nodes", func() {
+		nodes, err := ocputils.GetNodesByLabel(config, "nvidia.com/gpu.present=true")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodes.Items).ToNot(BeEmpty(), "No GPU nodes found")
+		gpuNodes = nodes.Items
+		for _, node := range gpuNodes {
+			val := node.Status.Capacity[workloads.GpuResource]
+			if val.Value() > maxCapacity {
+				maxCapacity = val.Value()
+			}
+		}
+		Expect(maxCapacity).To(BeNumerically(">", 0), "GPU nodes have no GPU capacity")
+		testutils.Printf("Info", "found #%v GPU nodes, largest GPU capacity is %v", len(gpuNodes), maxCapacity)
+	})
+
+	It("create scheduling test namespace", func() {
+		ns, err := ocputils.CreateNamespace(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveAsJsonToArtifactsDir(ns, "gpu_scheduling_namespace.json")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("pod requesting more GPUs than any node has should stay Pending", func() {
+		_, pod := createProbe("gpu-too-many", maxCapacity+1)
+		pod, err := ocputils.CreatePod(config, pod)
+		Expect(err).ToNot(HaveOccurred())
+		waitForFailedScheduling(pod.Name, fmt.Sprintf("Insufficient %v", workloads.GpuResource))
+	})
+
+	It("pod without toleration should not be scheduled on tainted GPU nodes", func() {
+		tainted := true
+		for _, node := range gpuNodes {
+			hasTaint := false
+			for _, taint := range node.Spec.Taints {
+				if taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute {
+					hasTaint = true
+				}
+			}
+			tainted = tainted && hasTaint
+		}
+		if !tainted {
+			Skip("Not all GPU nodes are tainted")
+		}
+		_, pod := createProbe("gpu-no-toleration", 1)
+		pod.Spec.Tolerations = nil
+		pod, err := ocputils.CreatePod(config, pod)
+		Expect(err).ToNot(HaveOccurred())
+		// "untolerated taint" on recent versions, "that the pod didn't tolerate" on older ones
+		waitForFailedScheduling(pod.Name, "taint")
+	})
+
+	It("pod without GPU limit should see no GPU", func() {
+		probe, pod := createProbe("gpu-no-limit", 0)
+		delete(pod.Spec.Containers[0].Resources.Limits, workloads.GpuResource)
+		pod, err := ocputils.CreatePod(config, pod)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.ExecWithRetryBackoff("Wait for pod without GPU limit to finish", func() bool {
+			p, err := ocputils.GetPod(config, namespace, pod.Name)
+			if err != nil {
+				return false
+			}
+			pod = p
+			return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+		}, 20, 15*time.Second)
+		_ = testutils.SaveAsJsonToArtifactsDir(pod, fmt.Sprintf("pod_%v.json", pod.Name))
+		Expect(err).ToNot(HaveOccurred())
+		output, err := ocputils.GetPodLogs(config, *pod, false)
+		Expect(err).ToNot(HaveOccurred())
+		_ = testutils.SaveToArtifactsDir([]byte(*output), fmt.Sprintf("pod_%v_output.log", pod.Name))
+		result := probe.Parse(*output)
+		Expect(result.Passed).To(BeTrue(), "nvidia-smi sees GPUs without a %v limit:\n%v", workloads.GpuResource, *output)
+	})
+
+	It("remove scheduling test namespace", func() {
+		err := ocputils.DeleteNamespace(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.ExecWithRetryBackoff("Wait until namespace is deleted", func() bool {
+			_, err := ocputils.GetNamespace(config, namespace)
+			return errors.IsNotFound(err)
+		}, 60, 10*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+	"ci-tools-nvidia-gpu-operator/testutils/report"
+)
+
+// GPU operator install milestones, see MILESTONE_BUDGETS
+const (
+	milestoneCsvSucceeded       = "csv_succeeded"
+	milestoneClusterPolicyReady = "clusterpolicy_ready"
+	milestoneDriverReady        = "driver_ready"
+	milestoneGpuCapacity        = "gpu_capacity"
+	milestoneValidatorReady     = "validator_ready"
+
+	validatorPodLabel = "app=nvidia-operator-validator"
+)
+
+// Autoscaling milestones, timed from the creation of the pending GPU workload
+const (
+	milestoneAutoscalingMachineCreated    = "autoscaling_machine_created"
+	milestoneAutoscalingNodeReady         = "autoscaling_node_ready"
+	milestoneAutoscalingDriverReady       = "autoscaling_driver_ready"
+	milestoneAutoscalingDevicePluginReady = "autoscaling_device_plugin_ready"
+	milestoneAutoscalingGpuCapacity       = "autoscaling_gpu_capacity"
+	milestoneAutoscalingWorkloadStarted   = "autoscaling_workload_started"
+
+	devicePluginPodLabel = "app=nvidia-device-plugin-daemonset"
+)
+
+var installMilestones = []string{
+	milestoneCsvSucceeded,
+	milestoneClusterPolicyReady,
+	milestoneDriverReady,
+	milestoneGpuCapacity,
+	milestoneValidatorReady,
+}
+
+var autoscalingMilestones = []string{
+	milestoneAutoscalingMachineCreated,
+	milestoneAutoscalingNodeReady,
+	milestoneAutoscalingDriverReady,
+	milestoneAutoscalingDevicePluginReady,
+	milestoneAutoscalingGpuCapacity,
+	milestoneAutoscalingWorkloadStarted,
+}
+
+// milestoneRecorder times milestones from a start time, see installStartTime
+// for the GPU operator install.
+type milestoneRecorder struct {
+	start      time.Time
+	budgets    map[string]time.Duration
+	milestones []report.Milestone
+}
+
+// newMilestoneRecorder keeps the budgets of the named milestones, the other
+// budgets are those of another suite. A budget of a milestone no suite records
+// is an error, e.g. a typo.
+func newMilestoneRecorder(start time.Time, names []string) (*milestoneRecorder, error) {
+	budgets, err := report.ParseBudgets(internal.Config.MilestoneBudgets)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, name := range installMilestones {
+		known[name] = true
+	}
+	for _, name := range autoscalingMilestones {
+		known[name] = true
+	}
+	unknown := []string{}
+	for name := range budgets {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown milestones %v in MILESTONE_BUDGETS, the milestones are %v and %v", unknown, installMilestones, autoscalingMilestones)
+	}
+	r := &milestoneRecorder{start: start, budgets: map[string]time.Duration{}}
+	for _, name := range names {
+		if budget, ok := budgets[name]; ok {
+			r.budgets[name] = budget
+		}
+	}
+	testutils.Printf("Milestone", "Timing milestones from %v", start.UTC().Format(time.RFC3339))
+	return r, nil
+}
+
+// installStartTime returns the creation of the GPU operator Subscription, or
+// of its CSV when installed without OLM Subscription, e.g. as an addon.
+func installStartTime(config *rest.Config, csv *operatorsv1alpha1.ClusterServiceVersion) (time.Time, error) {
+	subs, err := ocputils.GetSubscriptions(config, csv.Namespace)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, sub := range subs.Items {
+		if sub.Status.InstalledCSV == csv.Name || strings.Contains(sub.Spec.Package, "gpu-operator") {
+			return sub.CreationTimestamp.Time, nil
+		}
+	}
+	return csv.CreationTimestamp.Time, nil
+}
+
+func (r *milestoneRecorder) record(name string, t time.Time, source string) {
+	for _, m := range r.milestones {
+		if m.Name == name {
+			return
+		}
+	}
+	m := report.Milestone{Name: name, Time: t, Duration: t.Sub(r.start), Source: source, Budget: r.budgets[name]}
+	r.milestones = append(r.milestones, m)
+	testutils.Printf("Milestone", "%v after %v (%v)", name, m.Duration.Round(time.Second), source)
+}
+
+// skip drops the budget of a milestone that cannot be reached on this
+// cluster, so that it is not reported as exceeded.
+func (r *milestoneRecorder) skip(name string, reason string) {
+	if _, ok := r.budgets[name]; ok {
+		delete(r.budgets, name)
+		testutils.Printf("Milestone", "%v skipped: %v", name, reason)
+	}
+}
+
+func (r *milestoneRecorder) save() error {
+	if err := testutils.SaveAsJsonToArtifactsDir(r.milestones, report.MilestonesFile); err != nil {
+		return err
+	}
+	return testutils.SaveToArtifactsDir(report.FormatMilestoneMetrics(r.milestones), report.MilestoneMetricsFile)
+}
+
+// exceeded returns the milestones over their budget, and the budgets of the
+// milestones that were not reached.
+func (r *milestoneRecorder) exceeded() []string {
+	exceeded := []string{}
+	for name, budget := range r.budgets {
+		reached := false
+		for _, m := range r.milestones {
+			if m.Name != name {
+				continue
+			}
+			reached = true
+			if m.Exceeded() {
+				exceeded = append(exceeded, fmt.Sprintf("%v took %v, budget %v", name, m.Duration.Round(time.Second), budget))
+			}
+		}
+		if !reached {
+			exceeded = append(exceeded, fmt.Sprintf("%v was not reached, budget %v", name, budget))
+		}
+	}
+	sort.Strings(exceeded)
+	return exceeded
+}
+
+// csvSucceededTime returns when the CSV first reached the Succeeded phase.
+func csvSucceededTime(csv *operatorsv1alpha1.ClusterServiceVersion) (time.Time, bool) {
+	for _, condition := range csv.Status.Conditions {
+		if condition.Phase == operatorsv1alpha1.CSVPhaseSucceeded && condition.LastTransitionTime != nil {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	if csv.Status.Phase == operatorsv1alpha1.CSVPhaseSucceeded && csv.Status.LastTransitionTime != nil {
+		return csv.Status.LastTransitionTime.Time, true
+	}
+	return time.Time{}, false
+}
+
+// clusterPolicyReadyTime returns when the ClusterPolicy Ready condition was
+// last set. The operator versions without status conditions set the state to
+// ready once all the operands are, so it falls back to when the last of the
+// operand pods of namespace became Ready.
+func clusterPolicyReadyTime(config *rest.Config, namespace string) (time.Time, string, error) {
+	clusterPolicies, err := ocputils.ListDynamicResource(config, gpuv1.GroupVersion.WithResource("clusterpolicies"))
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	if len(clusterPolicies.Items) != 1 {
+		return time.Time{}, "", fmt.Errorf("expected a single ClusterPolicy, found %v", len(clusterPolicies.Items))
+	}
+	conditions, _, err := unstructured.NestedSlice(clusterPolicies.Items[0].Object, "status", "conditions")
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" || condition["status"] != string(metav1.ConditionTrue) {
+			continue
+		}
+		transition, _ := condition["lastTransitionTime"].(string)
+		t, err := time.Parse(time.RFC3339, transition)
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("invalid ClusterPolicy Ready lastTransitionTime %q: %w", transition, err)
+		}
+		return t, "ClusterPolicy condition", nil
+	}
+	pods, err := ocputils.GetPodsByLabel(config, namespace, "app!=gpu-operator")
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	operands := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			operands = append(operands, pod)
+		}
+	}
+	readyTime, ok := podsReadyTime(operands)
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("operand pods of %v are not ready", namespace)
+	}
+	return readyTime, "operand pod conditions", nil
+}
+
+// gpuCapacityTime returns when the first device plugin pod on one of the nodes
+// with GPU capacity became Ready, as nodes have no capacity timestamp and the
+// device plugin advertises the capacity when it starts.
+func gpuCapacityTime(config *rest.Config, nodes []corev1.Node) (time.Time, bool) {
+	pods, err := ocputils.GetPodsByLabel(config, "", devicePluginPodLabel)
+	if err != nil {
+		return time.Time{}, false
+	}
+	withCapacity := map[string]bool{}
+	for _, node := range nodes {
+		if capacity, ok := node.Status.Capacity["nvidia.com/gpu"]; ok && !capacity.IsZero() {
+			withCapacity[node.Name] = true
+		}
+	}
+	var first time.Time
+	for _, pod := range pods.Items {
+		if !withCapacity[pod.Spec.NodeName] {
+			continue
+		}
+		if readyTime, ok := podsReadyTime([]corev1.Pod{pod}); ok && (first.IsZero() || readyTime.Before(first)) {
+			first = readyTime
+		}
+	}
+	return first, !first.IsZero()
+}
+
+// podsReadyTime returns when the last of the pods became Ready, false when
+// there are no pods or one of them is not Ready.
+func podsReadyTime(pods []corev1.Pod) (time.Time, bool) {
+	var readyTime time.Time
+	for _, pod := range pods {
+		ready := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready = true
+				if condition.LastTransitionTime.After(readyTime) {
+					readyTime = condition.LastTransitionTime.Time
+				}
+			}
+		}
+		if !ready {
+			return time.Time{}, false
+		}
+	}
+	return readyTime, len(pods) > 0
+}
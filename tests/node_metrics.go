@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/tests/workloads"
+	"ci-tools-nvidia-gpu-operator/testutils/inventory"
+	"ci-tools-nvidia-gpu-operator/testutils/metrics"
+)
+
+const deployLabelPrefix = "nvidia.com/gpu.deploy."
+
+// nodeReadyMetrics are the node-status-exporter readiness metrics, by the
+// component name of the nvidia.com/gpu.deploy.<component> node label.
+var nodeReadyMetrics = map[string]string{
+	"driver":            "gpu_operator_node_driver_ready",
+	"container-toolkit": "gpu_operator_node_toolkit_ready",
+	"device-plugin":     "gpu_operator_node_device_plugin_ready",
+}
+
+// podScrape is the metrics endpoint response of a pod.
+type podScrape struct {
+	Pod      corev1.Pod
+	Body     []byte
+	Families metrics.Families
+	Err      error
+}
+
+// scrapePods scrapes the metrics of all the pods concurrently. The scrapes
+// are in the order of the pods.
+func scrapePods(config *rest.Config, pods []corev1.Pod, port string) []podScrape {
+	scrapes := make([]podScrape, len(pods))
+	var wg sync.WaitGroup
+	for i := range pods {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scrape := podScrape{Pod: pods[i]}
+			scrape.Body, scrape.Err = ocputils.PodProxyGet(config, pods[i], port, "metrics", map[string]string{})
+			if scrape.Err == nil {
+				scrape.Families, scrape.Err = metrics.Parse(string(scrape.Body))
+			}
+			scrapes[i] = scrape
+		}(i)
+	}
+	wg.Wait()
+	return scrapes
+}
+
+// nodeMetricsRow compares the node-status-exporter metrics of a node to the
+// node labels and GPU capacity. The device count is not compared to the
+// capacity when the node shares its GPUs, with time-slicing, MPS or MIG.
+type nodeMetricsRow struct {
+	Node        string             `json:"node"`
+	Pod         string             `json:"pod"`
+	Deploy      map[string]string  `json:"deploy"`
+	Ready       map[string]float64 `json:"ready"`
+	Devices     float64            `json:"devices"`
+	GpuCapacity int64              `json:"gpu_capacity"`
+	SharesGpus  bool               `json:"shares_gpus"`
+	Errors      []string           `json:"errors,omitempty"`
+}
+
+func newNodeMetricsRow(node *corev1.Node, scrape podScrape) nodeMetricsRow {
+	row := nodeMetricsRow{
+		Node:   node.Name,
+		Pod:    scrape.Pod.Name,
+		Deploy: map[string]string{},
+		Ready:  map[string]float64{},
+	}
+	capacity := node.Status.Capacity[workloads.GpuResource]
+	row.GpuCapacity = capacity.Value()
+	row.SharesGpus = inventory.NewNodeGpus(*node).SharesGpus()
+	if scrape.Err != nil {
+		row.Errors = append(row.Errors, scrape.Err.Error())
+		return row
+	}
+	components := []string{}
+	for component := range nodeReadyMetrics {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		name := nodeReadyMetrics[component]
+		deploy := node.Labels[deployLabelPrefix+component]
+		row.Deploy[component] = deploy
+		samples := scrape.Families.Samples(name, nil)
+		if len(samples) != 1 {
+			row.Errors = append(row.Errors, fmt.Sprintf("expected a single %v sample, got %v", name, len(samples)))
+			continue
+		}
+		row.Ready[component] = samples[0].Value
+		// Components with deploy label "false" are not managed by the operator
+		if deploy != "false" && samples[0].Value != 1 {
+			row.Errors = append(row.Errors, fmt.Sprintf("%v is %v while %v%v=%v", name, samples[0].Value, deployLabelPrefix, component, deploy))
+		}
+	}
+	devices := scrape.Families.Samples("gpu_operator_node_device_plugin_devices_total", nil)
+	if len(devices) != 1 {
+		row.Errors = append(row.Errors, fmt.Sprintf("expected a single gpu_operator_node_device_plugin_devices_total sample, got %v", len(devices)))
+		return row
+	}
+	row.Devices = devices[0].Value
+	if !row.SharesGpus && int64(row.Devices) != row.GpuCapacity {
+		row.Errors = append(row.Errors, fmt.Sprintf("device plugin reports %v devices, node %v capacity is %v", row.Devices, workloads.GpuResource, row.GpuCapacity))
+	}
+	return row
+}
+
+// nodeMetricsTable formats the rows as a text table, one line per node.
+func nodeMetricsTable(rows []nodeMetricsRow) []byte {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	components := []string{}
+	for component := range nodeReadyMetrics {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	fmt.Fprint(w, "NODE\tPOD")
+	for _, component := range components {
+		fmt.Fprintf(w, "\t%v (DEPLOY/READY)", component)
+	}
+	fmt.Fprint(w, "\tDEVICES\tCAPACITY\tERRORS\n")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%v\t%v", row.Node, row.Pod)
+		for _, component := range components {
+			ready := "-"
+			if val, ok := row.Ready[component]; ok {
+				ready = strconv.FormatFloat(val, 'f', -1, 64)
+			}
+			fmt.Fprintf(w, "\t%v/%v", row.Deploy[component], ready)
+		}
+		capacity := strconv.FormatInt(row.GpuCapacity, 10)
+		if row.SharesGpus {
+			capacity += " (shared)"
+		}
+		fmt.Fprintf(w, "\t%v\t%v\t%v\n", row.Devices, capacity, len(row.Errors))
+	}
+	w.Flush()
+	return buf.Bytes()
+}
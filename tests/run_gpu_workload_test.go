@@ -2,137 +2,323 @@ package tests
 
 import (
 	"fmt"
-	"strings"
+	"sort"
+	"strconv"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 
 	"ci-tools-nvidia-gpu-operator/internal"
 	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/tests/workloads"
 	"ci-tools-nvidia-gpu-operator/testutils"
 )
 
 var _ = Describe("run_gpu_workload :", Ordered, func() {
 	var (
-		config        *rest.Config
-		namespace     string
-		gpuBurnImage  string
-		daemonsetName string
+		config      *rest.Config
+		namespace   string
+		workload    workloads.Workload
+		duration    time.Duration
+		gpuCapacity map[string]int64
+		results     map[string]workloads.Result
+		podLogs     map[string]string
+		podNodes    map[string]string
+		podGpus     map[string]int64
 	)
 
 	BeforeAll(func() {
-		namespace = "gpu-burn-test"
-		gpuBurnImage = "quay.io/openshift-psap/gpu-burn"
-		daemonsetName = "gpu-burn-daemonset"
+		namespace = internal.GpuBurnNamespace
+		testutils.AddDiagnosticsNamespace(namespace)
+		results = map[string]workloads.Result{}
+		podLogs = map[string]string{}
+		podNodes = map[string]string{}
+		podGpus = map[string]int64{}
 
 		config = internal.GetClientConfig()
-	})
 
-	It("create gpu-burn namespace", func() {
-		ns, err := ocputils.CreateNamespace(config, namespace)
-		Expect(err).ToNot(HaveOccurred())
-		err = testutils.SaveAsJsonToArtifactsDir(ns, "gpu_burn_namespace.json")
+		mode := internal.Config.GpuWorkloadMode
+		Expect(mode).To(BeElementOf(workloads.ModeJob, workloads.ModeDaemonSet), "Invalid workload mode")
+
+		seconds, err := strconv.ParseInt(internal.Config.GpuBurnDuration, 10, 64)
+		Expect(err).ToNot(HaveOccurred(), "Invalid burn duration value")
+		duration = time.Duration(seconds) * time.Second
+		workload, err = workloads.Get(internal.Config.GpuWorkload, workloads.Options{
+			Image:    internal.Config.GpuWorkloadImage,
+			Duration: duration,
+		})
 		Expect(err).ToNot(HaveOccurred())
+		if requirer, ok := workload.(workloads.ComputeCapabilityRequirer); ok {
+			major, minor := requirer.MinComputeCapability()
+			inv, err := testutils.GetGpuInventory(config)
+			Expect(err).ToNot(HaveOccurred())
+			// Without GFD labels the compute capability is unknown, run anyway
+			if len(inv.GfdNodes()) > 0 && !inv.MinComputeCapability(major, minor) {
+				Skip(fmt.Sprintf("%v needs GPUs of compute capability %v.%v or later on all the GPU nodes", workload.Name(), major, minor))
+			}
+		}
+		testutils.Printf("Info", "Running workload %v with image %v as %v", workload.Name(), workload.Image(), internal.Config.GpuWorkloadMode)
 	})
 
-	It("create gpu-burn ConfigNap", func() {
-		cm := &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "gpu-burn-entrypoint",
-				Namespace: namespace,
-			},
-			Data: map[string]string{
-				"entrypoint.sh": `#!/bin/bash
-                                  NUM_GPUS=$(nvidia-smi -L | wc -l)
-                                  if [ $NUM_GPUS -eq 0 ]; then
-                                    echo "ERROR No GPUs found"
-                                    exit 1
-                                  fi
-                                  /usr/local/bin/gpu-burn 300
-                                  if [ ! $? -eq 0 ]; then 
-                                    exit 1
-                                  fi`,
-			},
+	// checkBurnUtilisation must run while the workload is running
+	checkBurnUtilisation := func() {
+		if workload.Name() != workloads.GpuBurnName {
+			Skip(fmt.Sprintf("Workload is %v, not %v", workload.Name(), workloads.GpuBurnName))
+		}
+		prom, err := ocputils.NewThanosQuerierClient(config)
+		Expect(err).ToNot(HaveOccurred())
+		nodes := []string{}
+		for node := range gpuCapacity {
+			nodes = append(nodes, node)
 		}
-		cm, err := ocputils.CreateConfigMap(config, cm)
+		// The burn starts once the image is pulled
+		maxRetries := int((duration + 10*time.Minute) / (30 * time.Second))
+		utilisation, err := waitForGpuUtilisation(config, prom, nodes, burnGpuUtil, maxRetries)
+		_ = testutils.SaveAsJsonToArtifactsDir(utilisation, "gpu_burn_gpu_utilisation.json")
 		Expect(err).ToNot(HaveOccurred())
-		err = testutils.SaveAsJsonToArtifactsDir(cm, "gpu_burn_configmap.json")
+	}
+
+	It("get GPU capacity of GPU nodes", func() {
+		nodes, err := ocputils.GetNodesByLabel(config, "nvidia.com/gpu.present=true")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodes.Items).ToNot(BeEmpty(), "No GPU nodes found")
+		gpuCapacity = map[string]int64{}
+		for _, node := range nodes.Items {
+			val, ok := node.Status.Capacity[workloads.GpuResource]
+			Expect(ok).To(BeTrue(), "node %v has no %v capacity", node.Name, workloads.GpuResource)
+			gpuCapacity[node.Name] = val.Value()
+		}
+		testutils.Printf("Info", "GPU capacity per node: %v", gpuCapacity)
+		err = testutils.SaveAsJsonToArtifactsDir(gpuCapacity, "gpu_capacity.json")
 		Expect(err).ToNot(HaveOccurred())
 	})
 
-	It("create gpu-burn DaemonSet", func() {
-		ds := newBurnDaemonSet(namespace, daemonsetName, gpuBurnImage)
-		ds, err := ocputils.CreatDaemonSet(config, ds)
+	It("create gpu-burn namespace", func() {
+		ns, err := ocputils.CreateNamespace(config, namespace)
 		Expect(err).ToNot(HaveOccurred())
-		err = testutils.SaveAsJsonToArtifactsDir(ds, "gpu_burn_daemonset.json")
+		err = testutils.SaveAsJsonToArtifactsDir(ns, "gpu_burn_namespace.json")
 		Expect(err).ToNot(HaveOccurred())
 	})
 
-	It("daemon set should run", func() {
-		var ds *appsv1.DaemonSet
-		err := testutils.ExecWithRetryBackoff("DaemonSet state check. Desired vs Ready", func() bool {
-			var err error
-			ds, err = ocputils.GetDaemonset(config, namespace, daemonsetName)
-			if err != nil {
-				return false
-			}
-			return ds.Status.DesiredNumberScheduled != 0 && ds.Status.NumberReady == ds.Status.DesiredNumberScheduled
-		}, 20, 30*time.Second)
-		Expect(err).ToNot(HaveOccurred(), "Desired != Ready or desired is 0.")
-		err = testutils.SaveAsJsonToArtifactsDir(ds, "gpu_burn_daemonset.json")
+	It("create workload entrypoint ConfigMap", func() {
+		cm, err := ocputils.CreateConfigMap(config, workloads.NewConfigMap(namespace, workload))
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveAsJsonToArtifactsDir(cm, fmt.Sprintf("%v_configmap.json", workload.Name()))
 		Expect(err).ToNot(HaveOccurred())
 	})
 
-	It("should run burn to completion on all nodes", func() {
-		var pods *corev1.PodList
-		err := testutils.ExecWithRetryBackoff("Get Daemonset pods", func() bool {
-			var err error
-			pods, err = ocputils.GetPodsByLabel(config, namespace, "app=gpu-burn-daemonset")
-			if err != nil {
-				return false
+	Context("as a DaemonSet", Ordered, func() {
+		BeforeAll(func() {
+			if internal.Config.GpuWorkloadMode != workloads.ModeDaemonSet {
+				Skip(fmt.Sprintf("Workload mode is %v", internal.Config.GpuWorkloadMode))
 			}
-			if len(pods.Items) == 0 {
-				return false // Pods not ready yet
+		})
+
+		It("create workload DaemonSet", func() {
+			// A DaemonSet has a single pod template, request the GPUs available on every node
+			var gpus int64
+			for _, capacity := range gpuCapacity {
+				if gpus == 0 || capacity < gpus {
+					gpus = capacity
+				}
 			}
-			return true
-		}, 15, 30*time.Second)
-		Expect(err).ToNot(HaveOccurred())
-		Expect(pods.Items).ToNot(BeEmpty())
-		podStates := map[string]bool{}
-		err = testutils.ExecWithRetryBackoff("Wait for GPU Burn to finish", func() bool {
-			for _, pod := range pods.Items {
-				if val, ok := podStates[pod.Name]; ok && val {
-					continue
+			ds, err := ocputils.CreateDaemonSet(config, workloads.NewDaemonSet(namespace, workload, gpus))
+			Expect(err).ToNot(HaveOccurred())
+			err = testutils.SaveAsJsonToArtifactsDir(ds, fmt.Sprintf("%v_daemonset.json", workload.Name()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("daemon set should run", func() {
+			var ds *appsv1.DaemonSet
+			err := testutils.ExecWithRetryBackoff("DaemonSet state check. Desired vs Ready", func() bool {
+				var err error
+				ds, err = ocputils.GetDaemonset(config, namespace, workload.Name())
+				if err != nil {
+					return false
 				}
-				output_resp, err := ocputils.GetPodLogs(config, pod, true)
+				return ds.Status.DesiredNumberScheduled != 0 && ds.Status.NumberReady == ds.Status.DesiredNumberScheduled
+			}, 20, 30*time.Second)
+			Expect(err).ToNot(HaveOccurred(), "Desired != Ready or desired is 0.")
+			err = testutils.SaveAsJsonToArtifactsDir(ds, fmt.Sprintf("%v_daemonset.json", workload.Name()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("GPU utilisation should rise while gpu-burn runs", func() {
+			checkBurnUtilisation()
+		})
+
+		It("should run workload to completion on all nodes", func() {
+			var pods *corev1.PodList
+			err := testutils.ExecWithRetryBackoff("Get Daemonset pods", func() bool {
+				var err error
+				pods, err = ocputils.GetPodsByLabel(config, namespace, workloads.LabelSelector(workload))
 				if err != nil {
 					return false
 				}
-				output := *output_resp
-				filename := fmt.Sprintf("pod_%v_output.log", pod.Name)
-				_ = testutils.SaveToArtifactsDir([]byte(output), filename)
-				match1 := strings.Contains(output, "GPU 0: OK")
-				match2 := strings.Contains(output, "100.0%  proc'd:")
-				podStates[pod.Name] = match1 && match2
+				if len(pods.Items) == 0 {
+					return false // Pods not ready yet
+				}
+				return true
+			}, 15, 30*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pods.Items).ToNot(BeEmpty())
+			err = testutils.ExecWithRetryBackoff(fmt.Sprintf("Wait for %v to finish", workload.Name()), func() bool {
+				for _, pod := range pods.Items {
+					if _, ok := results[pod.Name]; ok {
+						continue
+					}
+					output_resp, err := ocputils.GetPodLogs(config, pod, false)
+					if err != nil {
+						return false
+					}
+					output := *output_resp
+					filename := fmt.Sprintf("pod_%v_output.log", pod.Name)
+					_ = testutils.SaveToArtifactsDir([]byte(output), filename)
+					exitCode, finished := workloads.ExitCodeFromLogs(output)
+					if !finished {
+						continue
+					}
+					podLogs[pod.Name] = output
+					podNodes[pod.Name] = pod.Spec.NodeName
+					podGpus[pod.Name] = workloads.RequestedGpus(pod)
+					results[pod.Name] = newWorkloadResult(workload, pod, output, exitCode)
+				}
+				return len(results) == len(pods.Items)
+			}, 60, 1*time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("as per-node Jobs", Ordered, func() {
+		var (
+			jobs     []*batchv1.Job
+			deadline time.Duration
+		)
+
+		BeforeAll(func() {
+			if internal.Config.GpuWorkloadMode != workloads.ModeJob {
+				Skip(fmt.Sprintf("Workload mode is %v", internal.Config.GpuWorkloadMode))
 			}
-			if len(podStates) != len(pods.Items) {
-				return false
+			// Leave time for pulling the image on top of the workload run time
+			deadline = duration + 30*time.Minute
+		})
+
+		It("create a workload Job for every GPU node", func() {
+			nodes := []string{}
+			for node := range gpuCapacity {
+				nodes = append(nodes, node)
 			}
-			for _, ok := range podStates {
-				if !ok {
-					return false
+			sort.Strings(nodes)
+			for i, node := range nodes {
+				job, err := ocputils.CreateJob(config, workloads.NewJob(namespace, workload, i, node, gpuCapacity[node], deadline))
+				Expect(err).ToNot(HaveOccurred())
+				testutils.Printf("Info", "Job %v runs on node %v with %v GPUs", job.Name, node, gpuCapacity[node])
+				jobs = append(jobs, job)
+			}
+			err := testutils.SaveAsJsonToArtifactsDir(jobs, fmt.Sprintf("%v_jobs.json", workload.Name()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("GPU utilisation should rise while gpu-burn runs", func() {
+			checkBurnUtilisation()
+		})
+
+		It("Jobs should finish", func() {
+			finished := map[string]bool{}
+			err := testutils.ExecWithRetryBackoff(fmt.Sprintf("Wait for %v Jobs to finish", workload.Name()), func() bool {
+				for i, job := range jobs {
+					if finished[job.Name] {
+						continue
+					}
+					j, err := ocputils.GetJob(config, namespace, job.Name)
+					if err != nil {
+						return false
+					}
+					jobs[i] = j
+					if condition := ocputils.GetJobFinishedCondition(j); condition != nil {
+						testutils.Printf("Info", "Job %v finished: %v %v", j.Name, condition.Type, condition.Message)
+						finished[j.Name] = true
+					}
+				}
+				return len(finished) == len(jobs)
+			}, int(deadline/(30*time.Second))+1, 30*time.Second)
+			_ = testutils.SaveAsJsonToArtifactsDir(jobs, fmt.Sprintf("%v_jobs.json", workload.Name()))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("collect Job results", func() {
+			for _, job := range jobs {
+				pods, err := ocputils.GetPodsByLabel(config, namespace, fmt.Sprintf("job-name=%v", job.Name))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pods.Items).ToNot(BeEmpty(), "No pod found for Job %v", job.Name)
+				pod := pods.Items[0]
+				_ = testutils.SaveAsJsonToArtifactsDir(pod, fmt.Sprintf("pod_%v.json", pod.Name))
+				output := ""
+				output_resp, err := ocputils.GetPodLogs(config, pod, false)
+				if err == nil {
+					output = *output_resp
+				}
+				_ = testutils.SaveToArtifactsDir([]byte(output), fmt.Sprintf("pod_%v_output.log", pod.Name))
+				exitCode := -1
+				for _, status := range pod.Status.ContainerStatuses {
+					if status.State.Terminated != nil {
+						exitCode = int(status.State.Terminated.ExitCode)
+					}
 				}
+				podLogs[pod.Name] = output
+				podNodes[pod.Name] = pod.Spec.NodeName
+				podGpus[pod.Name] = workloads.RequestedGpus(pod)
+				result := newWorkloadResult(workload, pod, output, exitCode)
+				if condition := ocputils.GetJobFinishedCondition(job); condition == nil || condition.Type != batchv1.JobComplete {
+					result.Passed = false
+					if condition != nil {
+						result.Message = fmt.Sprintf("Job %v %v: %v", job.Name, condition.Reason, condition.Message)
+					}
+				}
+				results[pod.Name] = result
 			}
-			return true
+		})
+	})
 
-		}, 60, 1*time.Minute)
+	It("workload should pass on all nodes", func() {
+		err := testutils.SaveAsJsonToArtifactsDir(results, fmt.Sprintf("%v_results.json", workload.Name()))
 		Expect(err).ToNot(HaveOccurred())
+		Expect(results).ToNot(BeEmpty())
+		for _, result := range results {
+			Expect(result.Passed).To(BeTrue(), "%v failed on node %v: %v", result.Workload, result.Node, result.Message)
+		}
+	})
+
+	It("gpu-burn should pass on every GPU of every node", func() {
+		if workload.Name() != workloads.GpuBurnName {
+			Skip(fmt.Sprintf("Workload is %v, not %v", workload.Name(), workloads.GpuBurnName))
+		}
+		reports := []*workloads.GpuBurnReport{}
+		failures := []string{}
+		for podName, output := range podLogs {
+			report := workloads.ParseGpuBurnOutput(output)
+			report.Pod = podName
+			report.Node = podNodes[podName]
+			// The DaemonSet pods request the smallest GPU capacity of the nodes
+			if err := report.Verify(podGpus[podName]); err != nil {
+				failures = append(failures, err.Error())
+			}
+			reports = append(reports, report)
+		}
+		err := testutils.SaveAsJsonToArtifactsDir(reports, "gpu_burn_summary.json")
+		Expect(err).ToNot(HaveOccurred())
+		summaryCsv, err := workloads.GpuBurnReportsToCsv(reports)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveToArtifactsDir(summaryCsv, "gpu_burn_summary.csv")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reports).To(HaveLen(len(gpuCapacity)), "gpu-burn did not run on every GPU node")
+		Expect(failures).To(BeEmpty())
 	})
 
 	It("successfully remove bun test namespace", func() {
@@ -145,3 +331,18 @@ var _ = Describe("run_gpu_workload :", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 })
+
+func newWorkloadResult(workload workloads.Workload, pod corev1.Pod, output string, exitCode int) workloads.Result {
+	result := workload.Parse(output)
+	result.Node = pod.Spec.NodeName
+	result.Pod = pod.Name
+	if exitCode != 0 {
+		result.Passed = false
+		if len(result.Message) == 0 {
+			result.Message = fmt.Sprintf("%v exited with code %v", workload.Name(), exitCode)
+		}
+	}
+	testutils.Printf("Result", "%v on node %v: passed=%v throughput=%v %v",
+		result.Workload, result.Node, result.Passed, result.Throughput, result.Unit)
+	return result
+}
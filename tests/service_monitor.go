@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils"
+)
+
+// serviceMonitorTargets are the endpoints of a ServiceMonitor and the
+// namespaces of the Services it selects, empty for any namespace.
+type serviceMonitorTargets struct {
+	Endpoints  []ocputils.ServiceMonitorEndpoint `json:"endpoints"`
+	Namespaces []string                          `json:"namespaces,omitempty"`
+}
+
+// getServiceMonitorTargets checks that the ServiceMonitor selects Services,
+// in the namespaces of its namespaceSelector, exposing the named port of every
+// endpoint, or backed by pods with the container port of its targetPort. It
+// skips the spec when a targetPort cannot be resolved, for Services without
+// a pod selector.
+func getServiceMonitorTargets(config *rest.Config, namespace string, name string) (*serviceMonitorTargets, error) {
+	sm, err := ocputils.GetServiceMonitor(config, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	_ = testutils.SaveAsJsonToArtifactsDir(sm, fmt.Sprintf("servicemonitor_%v.json", name))
+	targets := &serviceMonitorTargets{Endpoints: sm.Spec.Endpoints}
+	switch {
+	case sm.Spec.NamespaceSelector.Any:
+	case len(sm.Spec.NamespaceSelector.MatchNames) > 0:
+		targets.Namespaces = sm.Spec.NamespaceSelector.MatchNames
+	default:
+		targets.Namespaces = []string{namespace}
+	}
+	if len(sm.Spec.Endpoints) == 0 {
+		return nil, fmt.Errorf("ServiceMonitor %v/%v has no endpoints", namespace, name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&sm.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("ServiceMonitor %v/%v has an empty selector", namespace, name)
+	}
+	services := []corev1.Service{}
+	// All the namespaces are listed at once for any namespace
+	listed := targets.Namespaces
+	if len(listed) == 0 {
+		listed = []string{""}
+	}
+	for _, ns := range listed {
+		list, err := ocputils.GetServicesByLabel(config, ns, selector.String())
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, list.Items...)
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("ServiceMonitor %v/%v selector %v matches no Service in namespaces %v", namespace, name, selector, targets.Namespaces)
+	}
+	for _, endpoint := range sm.Spec.Endpoints {
+		switch {
+		case len(endpoint.Port) > 0:
+			if !servicesHavePort(services, endpoint.Port) {
+				return nil, fmt.Errorf("no Service selected by ServiceMonitor %v/%v exposes port %q", namespace, name, endpoint.Port)
+			}
+		case endpoint.TargetPort != nil:
+			found, err := servicePodsHavePort(config, services, *endpoint.TargetPort)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("no pod of the Services selected by ServiceMonitor %v/%v has container port %v", namespace, name, endpoint.TargetPort.String())
+			}
+		default:
+			return nil, fmt.Errorf("ServiceMonitor %v/%v has an endpoint without port nor targetPort", namespace, name)
+		}
+	}
+	return targets, nil
+}
+
+func servicesHavePort(services []corev1.Service, port string) bool {
+	for _, service := range services {
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.Name == port {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// servicePodsHavePort returns whether a pod selected by one of the services
+// has a container port of the name or number of targetPort, as the Prometheus
+// operator matches targetPort against the container ports of the endpoints.
+func servicePodsHavePort(config *rest.Config, services []corev1.Service, targetPort intstr.IntOrString) (bool, error) {
+	for _, service := range services {
+		if len(service.Spec.Selector) == 0 {
+			Skip(fmt.Sprintf("Service %v/%v has no pod selector, its endpoints cannot be matched to targetPort %v", service.Namespace, service.Name, targetPort.String()))
+		}
+		pods, err := ocputils.GetPodsByLabel(config, service.Namespace, labels.SelectorFromSet(service.Spec.Selector).String())
+		if err != nil {
+			return false, err
+		}
+		for _, pod := range pods.Items {
+			for _, container := range pod.Spec.Containers {
+				for _, port := range container.Ports {
+					if (targetPort.Type == intstr.String && port.Name == targetPort.StrVal) ||
+						(targetPort.Type == intstr.Int && port.ContainerPort == targetPort.IntVal) {
+						return true, nil
+					}
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// waitForServiceMonitorJobs waits for the cluster Prometheus configuration
+// to have a scrape job for every endpoint of the ServiceMonitor. It skips the
+// spec when the targets were not resolved, see getServiceMonitorTargets.
+func waitForServiceMonitorJobs(config *rest.Config, namespace string, name string, targets *serviceMonitorTargets) error {
+	if targets == nil {
+		Skip(fmt.Sprintf("The targets of ServiceMonitor %v/%v were not resolved", namespace, name))
+	}
+	return testutils.ExecWithRetryBackoff(fmt.Sprintf("%v prometheus pickup", name), func() bool {
+		promConfig, promyml, err := ocputils.GetPrometheusConfig(config)
+		if len(promyml) > 0 {
+			_ = testutils.SaveToArtifactsDir([]byte(promyml), "prometheus.yaml.gz.txt")
+		}
+		if err != nil {
+			testutils.Printf("Error", "%v", err)
+			return false
+		}
+		jobs := []*ocputils.ServiceMonitorJob{}
+		for _, endpoint := range targets.Endpoints {
+			job, err := promConfig.FindServiceMonitorJob(namespace, name, endpoint, targets.Namespaces)
+			if err != nil {
+				testutils.Printf("Info", "%v", err)
+				return false
+			}
+			jobs = append(jobs, job)
+		}
+		_ = testutils.SaveAsJsonToArtifactsDir(jobs, fmt.Sprintf("prometheus_jobs_%v.json", name))
+		return true
+	}, 30, 30*time.Second)
+}
@@ -5,8 +5,27 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/testutils"
 )
 
+var _ = testutils.CollectDiagnosticsOnFailure()
+
+var eventRecorder *testutils.EventRecorder
+
+var _ = BeforeSuite(func() {
+	var err error
+	eventRecorder, err = testutils.StartEventRecorder(internal.GetClientConfig(), internal.EventNamespaces())
+	Expect(err).ToNot(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	if eventRecorder != nil {
+		Expect(eventRecorder.Stop()).To(Succeed())
+	}
+})
+
 func TestSuite(t *testing.T) {
 	suiteConfig, reportConfig := GinkgoConfiguration()
 	RegisterFailHandler(Fail)
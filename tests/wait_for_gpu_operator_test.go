@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"encoding/json"
 	"strings"
 	"time"
@@ -9,7 +10,6 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 
 	"ci-tools-nvidia-gpu-operator/internal"
@@ -23,6 +23,7 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 		namespace      string
 		gpuOperatorCsv *operatorsv1alpha1.ClusterServiceVersion
 		clusterpolicy  *gpuv1.ClusterPolicy
+		milestones     *milestoneRecorder
 	)
 
 	BeforeAll(func() {
@@ -43,6 +44,7 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 		}
 		Expect(gpuOperatorCsv).ToNot(BeNil(), "CSV not found")
 		Expect(namespace).ToNot(BeEmpty())
+		testutils.AddDiagnosticsNamespace(namespace)
 		testutils.Printf("Info", "GPU Operator name=%v namespace=%v version=%v", gpuOperatorCsv.Name, gpuOperatorCsv.Namespace, gpuOperatorCsv.Spec.Version.String())
 		if gpuOperatorCsv.Status.Phase != succeeded {
 			err = testutils.ExecWithRetryBackoff("Wait for CSV to be Succeeded", func() bool {
@@ -60,25 +62,25 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 		err = testutils.SaveToArtifactsDir([]byte(gpuOperatorCsv.Spec.Version.String()), "gpu_operator_version.txt")
 		Expect(err).ToNot(HaveOccurred())
+		start, err := installStartTime(config, gpuOperatorCsv)
+		Expect(err).ToNot(HaveOccurred())
+		milestones, err = newMilestoneRecorder(start, installMilestones)
+		Expect(err).ToNot(HaveOccurred())
+		if succeededTime, ok := csvSucceededTime(gpuOperatorCsv); ok {
+			milestones.record(milestoneCsvSucceeded, succeededTime, "CSV condition")
+		}
 	})
 
 	It("should have GPU Nodes", func() {
-		labelSelectors := []string{
-			"feature.node.kubernetes.io/pci-10de.present",
-			"feature.node.kubernetes.io/pci-0302_10de.present",
-			"feature.node.kubernetes.io/pci-0300_10de.present",
-		}
 		err := testutils.ExecWithRetryBackoff("Wait For GPU Nodes", func() bool {
-			for _, label := range labelSelectors {
-				resp, err := ocputils.GetNodesByLabel(config, label)
-				if err != nil {
-					return false
-				}
-				if len(resp.Items) > 0 {
-					testutils.Printf("Info", "found #%v GPU nodes", len(resp.Items))
-					_ = testutils.SaveAsJsonToArtifactsDir(resp.Items, "gpu_nodes_found.json")
-					return true
-				}
+			inv, err := testutils.GetGpuInventory(config)
+			if err != nil {
+				return false
+			}
+			if nfdNodes := inv.NfdNodes(); len(nfdNodes) > 0 {
+				testutils.Printf("Info", "found #%v GPU nodes", len(nfdNodes))
+				_ = testutils.SaveAsJsonToArtifactsDir(nfdNodes, "gpu_nodes_found.json")
+				return true
 			}
 			return false
 		}, 15, 30*time.Second)
@@ -86,12 +88,10 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 	})
 
 	It("should have a ClusterPolicy", func() {
-		resp, err := ocputils.ListDynamicResource(config, gpuv1.GroupVersion.WithResource("clusterpolicies"))
-		Expect(err).ToNot(HaveOccurred())
-		Expect(len(resp.Items)).To(Equal(1), "ClusterPolicies in this cluster does not equal 1")
-		clusterpolicy = &gpuv1.ClusterPolicy{}
-		err = runtime.DefaultUnstructuredConverter.FromUnstructured(resp.Items[0].UnstructuredContent(), clusterpolicy)
+		clusterPolicies, err := ocputils.List[gpuv1.ClusterPolicy](config, "", "")
 		Expect(err).ToNot(HaveOccurred())
+		Expect(len(clusterPolicies)).To(Equal(1), "ClusterPolicies in this cluster does not equal 1")
+		clusterpolicy = &clusterPolicies[0]
 		testutils.Printf("Info", "ClusterPolicy Found. Name=%v", clusterpolicy.Name)
 		cpJson, err := json.MarshalIndent(clusterpolicy, "", " ")
 		Expect(err).ToNot(HaveOccurred())
@@ -99,6 +99,35 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("ClusterPolicy should be ready", func() {
+		err := testutils.ExecWithRetryBackoff("Wait for the ClusterPolicy to be ready", func() bool {
+			cp, err := ocputils.GetClusterPolicy(config)
+			if err != nil {
+				testutils.Printf("Error", "%v", err)
+				return false
+			}
+			clusterpolicy = cp
+			return cp.Status.State == gpuv1.Ready
+		}, 60, 15*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "ClusterPolicy state is %v", clusterpolicy.Status.State)
+		readyTime, source, err := clusterPolicyReadyTime(config, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		milestones.record(milestoneClusterPolicyReady, readyTime, source)
+	})
+
+	It("driver pods should be ready", func() {
+		pods, err := ocputils.GetPodsByLabel(config, namespace, ocputils.DriverPodLabel)
+		Expect(err).ToNot(HaveOccurred())
+		if len(pods.Items) == 0 {
+			reason := "No driver pods, the driver is not deployed by the GPU operator"
+			milestones.skip(milestoneDriverReady, reason)
+			Skip(reason)
+		}
+		readyTime, ok := podsReadyTime(pods.Items)
+		Expect(ok).To(BeTrue(), "Driver pods are not ready")
+		milestones.record(milestoneDriverReady, readyTime, "pod condition")
+	})
+
 	It("nvidia-operator-validator Daemonset should be ready", func() {
 		err := testutils.ExecWithRetryBackoff("Wait for nvidia-operator-validator daemonset", func() bool {
 			ds, err := ocputils.GetDaemonset(config, namespace, "nvidia-operator-validator")
@@ -113,6 +142,11 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 			return true
 		}, 20, 30*time.Second)
 		Expect(err).ToNot(HaveOccurred())
+		pods, err := ocputils.GetPodsByLabel(config, namespace, validatorPodLabel)
+		Expect(err).ToNot(HaveOccurred())
+		if readyTime, ok := podsReadyTime(pods.Items); ok {
+			milestones.record(milestoneValidatorReady, readyTime, "pod condition")
+		}
 	})
 
 	It("GPU nodes should be labeled with nvidia.com/gpu.present=true", func() {
@@ -149,6 +183,9 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 				return false
 			}
 			if i > 0 {
+				if capacityTime, ok := gpuCapacityTime(config, resp.Items); ok {
+					milestones.record(milestoneGpuCapacity, capacityTime, "device plugin pod condition")
+				}
 				_ = testutils.SaveAsJsonToArtifactsDir(resp.Items[0], "gpu_capacity_found.json")
 				return true
 			}
@@ -157,10 +194,32 @@ var _ = Describe("wait_for_gpu_operator :", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("save the GPU inventory", func() {
+		inv, err := testutils.GetGpuInventory(config)
+		Expect(err).ToNot(HaveOccurred())
+		err = testutils.SaveGpuInventory(inv)
+		Expect(err).ToNot(HaveOccurred())
+		table := new(bytes.Buffer)
+		Expect(inv.WriteTable(table)).To(Succeed())
+		testutils.Printf("Info", "GPU inventory:\n%v", table.String())
+	})
+
 	It("capture namespace", func() {
 		ns, err := ocputils.GetNamespace(config, namespace)
 		Expect(err).ToNot(HaveOccurred())
 		err = testutils.SaveAsJsonToArtifactsDir(ns, "namespace.json")
 		Expect(err).ToNot(HaveOccurred())
 	})
+
+	It("save install milestones", func() {
+		err := milestones.save()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("install milestones should be within budget", func() {
+		if len(milestones.budgets) == 0 {
+			Skip("No milestone budget, set MILESTONE_BUDGETS")
+		}
+		Expect(milestones.exceeded()).To(BeEmpty(), "Install milestones over budget")
+	})
 })
@@ -2,31 +2,106 @@ package tests
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/rest"
 
 	"ci-tools-nvidia-gpu-operator/internal"
 	"ci-tools-nvidia-gpu-operator/ocputils"
 	"ci-tools-nvidia-gpu-operator/testutils"
+	"ci-tools-nvidia-gpu-operator/testutils/inventory"
 )
 
+// nfdRuleLabel is set by the NodeFeatureRule on the nodes with an NVIDIA PCI
+// device, NFD only allows its own label namespaces by default
+const nfdRuleLabel = "feature.node.kubernetes.io/ci-nvidia-gpu"
+
+// nodeNames returns the sorted names of the nodes matching labelSelector.
+func nodeNames(config *rest.Config, labelSelector string) ([]string, error) {
+	nodes, err := ocputils.GetNodesByLabel(config, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// expectedGpuNodes returns the sorted names of the nodes known to have GPUs
+// without NFD: the nodes of the MachineSets with GPUs, and the nodes with
+// nvidia.com/gpu capacity.
+func expectedGpuNodes(config *rest.Config) ([]string, error) {
+	gpuNodes := map[string]bool{}
+	machineSets, err := ocputils.GetWorkerMachineSets(config, internal.MachineApiNamespace)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		for _, ms := range machineSets.Items {
+			if gpuMachineSetCount(&ms) == 0 {
+				continue
+			}
+			machines, err := ocputils.GetMachinesByLabel(config, internal.MachineApiNamespace, fmt.Sprintf("%v=%v", ocputils.MachineSetLabel, ms.Name))
+			if err != nil {
+				return nil, err
+			}
+			for _, machine := range machines.Items {
+				if machine.Status.NodeRef != nil {
+					gpuNodes[machine.Status.NodeRef.Name] = true
+				}
+			}
+		}
+	}
+	nodes, err := ocputils.GetNodesByLabel(config, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes.Items {
+		if capacity, ok := node.Status.Capacity[inventory.GpuResource]; ok && capacity.Value() > 0 {
+			gpuNodes[node.Name] = true
+		}
+	}
+	names := []string{}
+	for name := range gpuNodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 var _ = Describe("wait_for_nfd_operator :", Ordered, func() {
 	var (
-		config         *rest.Config
-		namespace      string
-		nfdOperatorCsv *operatorsv1alpha1.ClusterServiceVersion
+		config          *rest.Config
+		namespace       string
+		nfdOperatorCsv  *operatorsv1alpha1.ClusterServiceVersion
+		nodeFeatureRule *ocputils.NodeFeatureRule
 	)
 
 	BeforeAll(func() {
 		config = internal.GetClientConfig()
 	})
 
+	AfterAll(func() {
+		if nodeFeatureRule != nil {
+			_ = ocputils.DeleteNodeFeatureRule(config, nodeFeatureRule)
+		}
+	})
+
 	It("NFD Operator Should Be Installed successfully", func() {
+		if internal.Config.NfdDeployMode == internal.NfdDeployModeUpstream {
+			Skip("NFD is deployed from the upstream manifests")
+		}
 		succeeded := operatorsv1alpha1.ClusterServiceVersionPhase("Succeeded")
 		csvs, err := ocputils.GetCsvsByLabel(config, "", "")
 		Expect(err).ToNot(HaveOccurred())
@@ -57,6 +132,22 @@ var _ = Describe("wait_for_nfd_operator :", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("upstream NFD should be running", func() {
+		if internal.Config.NfdDeployMode != internal.NfdDeployModeUpstream {
+			Skip("NFD is deployed by the NFD Operator")
+		}
+		namespace = internal.UpstreamNfdNamespace
+		testutils.AddDiagnosticsNamespace(namespace)
+		deployment, err := ocputils.GetDeployment(config, namespace, internal.UpstreamNfdMaster)
+		Expect(err).ToNot(HaveOccurred())
+		_ = testutils.SaveAsJsonToArtifactsDir(deployment, "nfd_master_deployment.json")
+		Expect(ocputils.DeploymentReady(deployment)).To(BeTrue(), "%v Deployment is not available", internal.UpstreamNfdMaster)
+		ds, err := ocputils.GetDaemonset(config, namespace, internal.UpstreamNfdWorker)
+		Expect(err).ToNot(HaveOccurred())
+		_ = testutils.SaveAsJsonToArtifactsDir(ds, "nfd_worker_daemonset.json")
+		Expect(ocputils.DaemonSetReady(ds)).To(BeTrue(), "%v DaemonSet is not ready", internal.UpstreamNfdWorker)
+	})
+
 	It("should have a NFD label on a node", func() {
 		nfdLabel := "nfd.node.kubernetes.io/feature-labels"
 		node, err := ocputils.GetFirstWorkerNode(config)
@@ -67,6 +158,86 @@ var _ = Describe("wait_for_nfd_operator :", Ordered, func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("NVIDIA PCI labels should be on exactly the GPU nodes", func() {
+		expected, err := expectedGpuNodes(config)
+		Expect(err).ToNot(HaveOccurred())
+		if len(expected) == 0 {
+			Skip("No GPU MachineSet nor GPU capacity to tell the GPU nodes")
+		}
+		var labeled []string
+		err = testutils.ExecWithRetryBackoff("Wait for the NVIDIA PCI labels", func() bool {
+			inv, err := testutils.GetGpuInventory(config)
+			if err != nil {
+				return false
+			}
+			labeled = []string{}
+			for _, n := range inv.NfdNodes() {
+				labeled = append(labeled, n.Node)
+			}
+			sort.Strings(labeled)
+			return reflect.DeepEqual(labeled, expected)
+		}, 10, 30*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "NVIDIA PCI labels are on %v, GPU nodes are %v", labeled, expected)
+	})
+
+	It("NodeFeatureRule should label the GPU nodes", func() {
+		inv, err := testutils.GetGpuInventory(config)
+		Expect(err).ToNot(HaveOccurred())
+		expected := []string{}
+		for _, n := range inv.NfdNodes() {
+			expected = append(expected, n.Node)
+		}
+		sort.Strings(expected)
+		// The rule labels no node when no node has an NVIDIA PCI device
+		Expect(expected).ToNot(BeEmpty(), "NFD found no NVIDIA PCI device")
+
+		rule := &ocputils.NodeFeatureRule{}
+		rule.Name = "ci-nvidia-gpu"
+		rule.Spec.Rules = []ocputils.NodeFeatureRuleSpecRule{
+			{
+				Name:   "nvidia gpu",
+				Labels: map[string]string{nfdRuleLabel: "true"},
+				MatchFeatures: []ocputils.FeatureMatcherTerm{
+					{
+						Feature: "pci.device",
+						MatchExpressions: map[string]ocputils.MatchExpression{
+							"vendor": {Op: "In", Value: []string{inventory.NvidiaVendorID}},
+						},
+					},
+				},
+			},
+		}
+		created, err := ocputils.CreateNodeFeatureRule(config, rule)
+		if errors.IsNotFound(err) {
+			Skip("NFD does not serve the NodeFeatureRule API")
+		}
+		Expect(err).ToNot(HaveOccurred())
+		nodeFeatureRule = created
+		_ = testutils.SaveAsJsonToArtifactsDir(nodeFeatureRule, "nodefeaturerule.json")
+
+		var labeled []string
+		err = testutils.ExecWithRetryBackoff("Wait for the NodeFeatureRule label", func() bool {
+			labeled, err = nodeNames(config, nfdRuleLabel+"=true")
+			return err == nil && reflect.DeepEqual(labeled, expected)
+		}, 10, 30*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "%v is on %v, NVIDIA PCI devices are on %v", nfdRuleLabel, labeled, expected)
+	})
+
+	It("NodeFeatureRule label should be removed with the rule", func() {
+		if nodeFeatureRule == nil {
+			Skip("No NodeFeatureRule")
+		}
+		err := ocputils.DeleteNodeFeatureRule(config, nodeFeatureRule)
+		Expect(err).ToNot(HaveOccurred())
+		nodeFeatureRule = nil
+		var labeled []string
+		err = testutils.ExecWithRetryBackoff("Wait for the NodeFeatureRule label to be removed", func() bool {
+			labeled, err = nodeNames(config, nfdRuleLabel)
+			return err == nil && len(labeled) == 0
+		}, 10, 30*time.Second)
+		Expect(err).ToNot(HaveOccurred(), "%v is still on %v", nfdRuleLabel, labeled)
+	})
+
 	It("capture namespace", func() {
 		ns, err := ocputils.GetNamespace(config, namespace)
 		Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,52 @@
+package workloads
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	dcgmDiagName         = "dcgm-diag"
+	dcgmDiagDefaultImage = "nvcr.io/nvidia/cloud-native/dcgm:3.3.0-1-ubuntu22.04"
+)
+
+var dcgmDiagFailRegex = regexp.MustCompile(`\|\s*Fail\s*`)
+
+type dcgmDiag struct {
+	image string
+}
+
+func NewDcgmDiag(image string) Workload {
+	return &dcgmDiag{
+		image: imageOrDefault(image, dcgmDiagDefaultImage),
+	}
+}
+
+func (d *dcgmDiag) Name() string {
+	return dcgmDiagName
+}
+
+func (d *dcgmDiag) Image() string {
+	return d.image
+}
+
+// Entrypoint starts an embedded host engine, as the pod has no access to the
+// one running in the dcgm DaemonSet, and runs the short (level 1) diagnostic.
+func (d *dcgmDiag) Entrypoint() string {
+	return `#!/bin/bash
+nv-hostengine -n &
+sleep 5
+dcgmi diag -r 1`
+}
+
+func (d *dcgmDiag) Parse(logs string) Result {
+	result := Result{
+		Workload: dcgmDiagName,
+	}
+	failed := dcgmDiagFailRegex.MatchString(logs)
+	result.Passed = !failed && strings.Contains(logs, "Pass")
+	if !result.Passed {
+		result.Message = "dcgmi diag reported a failing test"
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package workloads
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	GpuBurnName         = "gpu-burn"
+	gpuBurnDefaultImage = "quay.io/openshift-psap/gpu-burn"
+)
+
+type gpuBurn struct {
+	image    string
+	duration time.Duration
+}
+
+func NewGpuBurn(image string, duration time.Duration) Workload {
+	return &gpuBurn{
+		image:    imageOrDefault(image, gpuBurnDefaultImage),
+		duration: duration,
+	}
+}
+
+func (g *gpuBurn) Name() string {
+	return GpuBurnName
+}
+
+func (g *gpuBurn) Image() string {
+	return g.image
+}
+
+func (g *gpuBurn) Entrypoint() string {
+	return fmt.Sprintf(`#!/bin/bash
+NUM_GPUS=$(nvidia-smi -L | wc -l)
+if [ $NUM_GPUS -eq 0 ]; then
+  echo "ERROR No GPUs found"
+  exit 1
+fi
+/usr/local/bin/gpu-burn %d`, int64(g.duration.Seconds()))
+}
+
+// Parse passes when every GPU seen by gpu-burn is OK, the reported throughput
+// is the sum of the last Gflop/s sample of each GPU.
+func (g *gpuBurn) Parse(logs string) Result {
+	result := Result{
+		Workload: GpuBurnName,
+		Unit:     "Gflop/s",
+	}
+	report := ParseGpuBurnOutput(logs)
+	for _, gpu := range report.Gpus {
+		result.Throughput += gpu.FinalGflops
+	}
+	err := report.Verify(int64(len(report.Gpus)))
+	result.Passed = err == nil && len(report.Gpus) > 0
+	if err != nil {
+		result.Message = err.Error()
+	} else if !result.Passed {
+		result.Message = "gpu-burn did not report any GPU"
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+package workloads
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	GpuBurnStatusOK      = "OK"
+	GpuBurnStatusFaulty  = "FAULTY"
+	GpuBurnStatusMissing = "MISSING"
+)
+
+var (
+	gpuBurnDeviceRegex   = regexp.MustCompile(`^GPU (\d+): (.+?) \(UUID: (.+)\)$`)
+	gpuBurnProgressRegex = regexp.MustCompile(`^([0-9.]+)%\s+proc'd:(.*?)errors:(.*?)temps:(.*)$`)
+	gpuBurnCalcsRegex    = regexp.MustCompile(`(\d+) \(([0-9.]+) Gflop/s\)`)
+	gpuBurnIntRegex      = regexp.MustCompile(`^(\d+)`)
+	gpuBurnStatusRegex   = regexp.MustCompile(`^GPU (\d+): (OK|FAULTY)$`)
+	gpuBurnTestedRegex   = regexp.MustCompile(`^Tested (\d+) GPUs:$`)
+)
+
+// GpuBurnSample is a single progress line of gpu-burn for one GPU.
+type GpuBurnSample struct {
+	Progress float64 `json:"progress"`
+	Gflops   float64 `json:"gflops"`
+	Errors   int64   `json:"errors"`
+	TempC    int64   `json:"temp_c,omitempty"`
+}
+
+type GpuBurnGpu struct {
+	Index       int             `json:"index"`
+	Model       string          `json:"model,omitempty"`
+	UUID        string          `json:"uuid,omitempty"`
+	Status      string          `json:"status"`
+	Errors      int64           `json:"errors"`
+	MaxTempC    int64           `json:"max_temp_c"`
+	FinalGflops float64         `json:"final_gflops"`
+	MaxGflops   float64         `json:"max_gflops"`
+	Samples     []GpuBurnSample `json:"samples"`
+}
+
+// GpuBurnReport holds the parsed gpu-burn output of a single node.
+type GpuBurnReport struct {
+	Node         string       `json:"node"`
+	Pod          string       `json:"pod"`
+	TestedGpus   int          `json:"tested_gpus"`
+	ExpectedGpus int64        `json:"expected_gpus"`
+	Completed    bool         `json:"completed"`
+	Gpus         []GpuBurnGpu `json:"gpus"`
+}
+
+// ParseGpuBurnOutput parses gpu-burn logs, where carriage returns were
+// already replaced by new lines, into a per-GPU report.
+func ParseGpuBurnOutput(logs string) *GpuBurnReport {
+	report := &GpuBurnReport{}
+	gpus := map[int]*GpuBurnGpu{}
+	getGpu := func(index int) *GpuBurnGpu {
+		if gpu, ok := gpus[index]; ok {
+			return gpu
+		}
+		gpu := &GpuBurnGpu{Index: index, Status: GpuBurnStatusMissing}
+		gpus[index] = gpu
+		return gpu
+	}
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimSpace(line)
+		if match := gpuBurnStatusRegex.FindStringSubmatch(line); match != nil {
+			index, _ := strconv.Atoi(match[1])
+			getGpu(index).Status = match[2]
+			continue
+		}
+		if match := gpuBurnDeviceRegex.FindStringSubmatch(line); match != nil {
+			index, _ := strconv.Atoi(match[1])
+			gpu := getGpu(index)
+			gpu.Model = match[2]
+			gpu.UUID = match[3]
+			continue
+		}
+		if match := gpuBurnTestedRegex.FindStringSubmatch(line); match != nil {
+			report.TestedGpus, _ = strconv.Atoi(match[1])
+			continue
+		}
+		match := gpuBurnProgressRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		progress, _ := strconv.ParseFloat(match[1], 64)
+		if progress >= 100 {
+			report.Completed = true
+		}
+		errorCounts := strings.Split(match[3], "-")
+		temps := strings.Split(match[4], "-")
+		for i, calc := range gpuBurnCalcsRegex.FindAllStringSubmatch(match[2], -1) {
+			sample := GpuBurnSample{Progress: progress}
+			sample.Gflops, _ = strconv.ParseFloat(calc[2], 64)
+			if i < len(errorCounts) {
+				sample.Errors = parseLeadingInt(errorCounts[i])
+			}
+			if i < len(temps) {
+				sample.TempC = parseLeadingInt(temps[i])
+			}
+			gpu := getGpu(i)
+			gpu.Samples = append(gpu.Samples, sample)
+			gpu.Errors = sample.Errors
+			gpu.FinalGflops = sample.Gflops
+			if sample.Gflops > gpu.MaxGflops {
+				gpu.MaxGflops = sample.Gflops
+			}
+			if sample.TempC > gpu.MaxTempC {
+				gpu.MaxTempC = sample.TempC
+			}
+		}
+	}
+	indexes := []int{}
+	for index := range gpus {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		report.Gpus = append(report.Gpus, *gpus[index])
+	}
+	return report
+}
+
+func parseLeadingInt(s string) int64 {
+	match := gpuBurnIntRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0
+	}
+	i, _ := strconv.ParseInt(match[1], 10, 64)
+	return i
+}
+
+// Verify returns an error unless the burn completed and every one of the
+// expected GPUs was reported OK.
+func (r *GpuBurnReport) Verify(expectedGpus int64) error {
+	r.ExpectedGpus = expectedGpus
+	if !r.Completed {
+		return fmt.Errorf("gpu-burn on node %v did not run to completion", r.Node)
+	}
+	if int64(r.TestedGpus) < expectedGpus {
+		return fmt.Errorf("gpu-burn on node %v tested %v GPUs, node capacity is %v", r.Node, r.TestedGpus, expectedGpus)
+	}
+	faulty := []string{}
+	for _, gpu := range r.Gpus {
+		if gpu.Status != GpuBurnStatusOK {
+			faulty = append(faulty, fmt.Sprintf("GPU %v: %v", gpu.Index, gpu.Status))
+		}
+	}
+	if len(faulty) > 0 {
+		return fmt.Errorf("gpu-burn on node %v reported %v", r.Node, strings.Join(faulty, ", "))
+	}
+	return nil
+}
+
+// GpuBurnReportsToCsv returns one line per node and GPU.
+func GpuBurnReportsToCsv(reports []*GpuBurnReport) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+	err := w.Write([]string{"node", "pod", "gpu", "model", "uuid", "status", "errors", "max_temp_c", "final_gflops", "max_gflops", "samples"})
+	if err != nil {
+		return nil, err
+	}
+	for _, report := range reports {
+		for _, gpu := range report.Gpus {
+			err = w.Write([]string{
+				report.Node,
+				report.Pod,
+				strconv.Itoa(gpu.Index),
+				gpu.Model,
+				gpu.UUID,
+				gpu.Status,
+				strconv.FormatInt(gpu.Errors, 10),
+				strconv.FormatInt(gpu.MaxTempC, 10),
+				strconv.FormatFloat(gpu.FinalGflops, 'f', -1, 64),
+				strconv.FormatFloat(gpu.MaxGflops, 'f', -1, 64),
+				strconv.Itoa(len(gpu.Samples)),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,70 @@
+package workloads
+
+import (
+	"strings"
+	"testing"
+)
+
+const twoGpuBurnOutput = `GPU 0: Tesla T4 (UUID: GPU-11111111-2222-3333-4444-555555555555)
+GPU 1: Tesla T4 (UUID: GPU-66666666-7777-8888-9999-000000000000)
+Initialized device 0 with 15109 MB of memory (14938 MB available, using 13444 MB of it), using FLOATS
+Initialized device 1 with 15109 MB of memory (14938 MB available, using 13444 MB of it), using FLOATS
+10.0%  proc'd: 3196 (3929 Gflop/s) - 3100 (3812 Gflop/s)   errors: 0 - 0   temps: 49 C - 50 C 
+50.0%  proc'd: 13583 (4102 Gflop/s) - 12800 (3950 Gflop/s)   errors: 0 - 12 (WARNING!)  temps: 58 C - 61 C 
+100.0%  proc'd: 26367 (4083 Gflop/s) - 25011 (3901 Gflop/s)   errors: 0 - 40 (WARNING!)  temps: 63 C - -- 
+Killing processes.. Freed memory for dev 0
+Freed memory for dev 1
+Uninitted cublas
+done
+
+Tested 2 GPUs:
+	GPU 0: OK
+	GPU 1: FAULTY
+workload exit code: 0
+`
+
+func TestParseGpuBurnOutput(t *testing.T) {
+	report := ParseGpuBurnOutput(twoGpuBurnOutput)
+	if !report.Completed {
+		t.Errorf("ParseGpuBurnOutput did not detect a completed run")
+	}
+	if report.TestedGpus != 2 || len(report.Gpus) != 2 {
+		t.Fatalf("ParseGpuBurnOutput returned wrong number of GPUs, expected: 2, got: tested=%v parsed=%v", report.TestedGpus, len(report.Gpus))
+	}
+	gpu0, gpu1 := report.Gpus[0], report.Gpus[1]
+	if gpu0.Model != "Tesla T4" || gpu0.UUID != "GPU-11111111-2222-3333-4444-555555555555" {
+		t.Errorf("ParseGpuBurnOutput returned wrong device, got: model=%v uuid=%v", gpu0.Model, gpu0.UUID)
+	}
+	if len(gpu0.Samples) != 3 || gpu0.FinalGflops != 4083 || gpu0.MaxGflops != 4102 || gpu0.MaxTempC != 63 {
+		t.Errorf("ParseGpuBurnOutput returned wrong samples for GPU 0, got: %+v", gpu0)
+	}
+	if gpu0.Status != GpuBurnStatusOK || gpu1.Status != GpuBurnStatusFaulty {
+		t.Errorf("ParseGpuBurnOutput returned wrong status, expected: OK/FAULTY, got: %v/%v", gpu0.Status, gpu1.Status)
+	}
+	if gpu1.Errors != 40 || gpu1.MaxTempC != 61 {
+		t.Errorf("ParseGpuBurnOutput returned wrong errors or temperature for GPU 1, got: errors=%v temp=%v", gpu1.Errors, gpu1.MaxTempC)
+	}
+}
+
+func TestGpuBurnReportVerify(t *testing.T) {
+	report := ParseGpuBurnOutput(twoGpuBurnOutput)
+	err := report.Verify(2)
+	if err == nil || !strings.Contains(err.Error(), "GPU 1: FAULTY") {
+		t.Errorf("Verify should report the faulty GPU, got: %v", err)
+	}
+
+	okOutput := strings.Replace(twoGpuBurnOutput, "GPU 1: FAULTY", "GPU 1: OK", 1)
+	report = ParseGpuBurnOutput(okOutput)
+	if err := report.Verify(2); err != nil {
+		t.Errorf("Verify returned an error for a passing run: %v", err)
+	}
+	// A node with 4 GPUs where only 2 were tested must fail
+	if err := report.Verify(4); err == nil {
+		t.Errorf("Verify should fail when fewer GPUs than the node capacity were tested")
+	}
+
+	report = ParseGpuBurnOutput("10.0%  proc'd: 3196 (3929 Gflop/s)   errors: 0   temps: 49 C\n")
+	if err := report.Verify(1); err == nil {
+		t.Errorf("Verify should fail for an incomplete run")
+	}
+}
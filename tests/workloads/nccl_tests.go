@@ -0,0 +1,60 @@
+package workloads
+
+import (
+	"regexp"
+	"strconv"
+)
+
+const (
+	ncclTestsName         = "nccl-tests"
+	ncclTestsDefaultImage = "ghcr.io/coreweave/nccl-tests:12.2.2-cudnn8-devel-ubuntu22.04-nccl2.19.3-1-2fa5ff8"
+)
+
+var (
+	ncclBusBandwidthRegex = regexp.MustCompile(`# Avg bus bandwidth\s*:\s*([0-9.]+)`)
+	ncclOutOfBoundsRegex  = regexp.MustCompile(`# Out of bounds values\s*:\s*(\d+)\s+OK`)
+)
+
+type ncclTests struct {
+	image string
+}
+
+func NewNcclTests(image string) Workload {
+	return &ncclTests{
+		image: imageOrDefault(image, ncclTestsDefaultImage),
+	}
+}
+
+func (n *ncclTests) Name() string {
+	return ncclTestsName
+}
+
+func (n *ncclTests) Image() string {
+	return n.image
+}
+
+func (n *ncclTests) Entrypoint() string {
+	return `#!/bin/bash
+NUM_GPUS=$(nvidia-smi -L | wc -l)
+if [ $NUM_GPUS -eq 0 ]; then
+  echo "ERROR No GPUs found"
+  exit 1
+fi
+/opt/nccl_tests/build/all_reduce_perf -b 8 -e 128M -f 2 -g $NUM_GPUS`
+}
+
+func (n *ncclTests) Parse(logs string) Result {
+	result := Result{
+		Workload: ncclTestsName,
+		Unit:     "GB/s",
+	}
+	if match := ncclBusBandwidthRegex.FindStringSubmatch(logs); match != nil {
+		result.Throughput, _ = strconv.ParseFloat(match[1], 64)
+	}
+	match := ncclOutOfBoundsRegex.FindStringSubmatch(logs)
+	result.Passed = match != nil && match[1] == "0"
+	if !result.Passed {
+		result.Message = "all_reduce_perf failed or reported out of bounds values"
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package workloads
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	pytorchName         = "pytorch-smoke"
+	pytorchDefaultImage = "nvcr.io/nvidia/pytorch:23.10-py3"
+	// The NGC PyTorch containers support compute capability 6.0 and later
+	pytorchMinComputeMajor = 6
+	pytorchMinComputeMinor = 0
+)
+
+var pytorchTflopsRegex = regexp.MustCompile(`TFLOPS: ([0-9.]+)`)
+
+type pytorch struct {
+	image string
+}
+
+func NewPytorch(image string) Workload {
+	return &pytorch{
+		image: imageOrDefault(image, pytorchDefaultImage),
+	}
+}
+
+func (p *pytorch) Name() string {
+	return pytorchName
+}
+
+func (p *pytorch) Image() string {
+	return p.image
+}
+
+func (p *pytorch) MinComputeCapability() (int, int) {
+	return pytorchMinComputeMajor, pytorchMinComputeMinor
+}
+
+// Entrypoint checks that CUDA is usable from PyTorch and times a batch of
+// half precision matrix multiplications.
+func (p *pytorch) Entrypoint() string {
+	return `#!/bin/bash
+python3 - <<'PYEOF'
+import sys, time
+import torch
+if not torch.cuda.is_available():
+    print("ERROR CUDA is not available")
+    sys.exit(1)
+print("Device:", torch.cuda.get_device_name(0))
+n, iterations = 8192, 50
+a = torch.randn(n, n, device="cuda", dtype=torch.float16)
+b = torch.randn(n, n, device="cuda", dtype=torch.float16)
+torch.cuda.synchronize()
+start = time.time()
+for _ in range(iterations):
+    c = a @ b
+torch.cuda.synchronize()
+elapsed = time.time() - start
+print("TFLOPS: %.2f" % (2 * n ** 3 * iterations / elapsed / 1e12))
+print("PYTORCH SMOKE TEST PASSED")
+PYEOF`
+}
+
+func (p *pytorch) Parse(logs string) Result {
+	result := Result{
+		Workload: pytorchName,
+		Unit:     "TFLOPS",
+	}
+	if match := pytorchTflopsRegex.FindStringSubmatch(logs); match != nil {
+		result.Throughput, _ = strconv.ParseFloat(match[1], 64)
+	}
+	result.Passed = strings.Contains(logs, "PYTORCH SMOKE TEST PASSED")
+	if !result.Passed {
+		result.Message = "PyTorch smoke test did not pass"
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package workloads
+
+import (
+	"strings"
+)
+
+const (
+	vectorAddName         = "cuda-vectoradd"
+	vectorAddDefaultImage = "nvcr.io/nvidia/k8s/cuda-sample:vectoradd-cuda11.7.1-ubi8"
+)
+
+type vectorAdd struct {
+	image string
+}
+
+func NewVectorAdd(image string) Workload {
+	return &vectorAdd{
+		image: imageOrDefault(image, vectorAddDefaultImage),
+	}
+}
+
+func (v *vectorAdd) Name() string {
+	return vectorAddName
+}
+
+func (v *vectorAdd) Image() string {
+	return v.image
+}
+
+func (v *vectorAdd) Entrypoint() string {
+	return `#!/bin/bash
+/cuda-samples/vectorAdd`
+}
+
+func (v *vectorAdd) Parse(logs string) Result {
+	result := Result{
+		Workload: vectorAddName,
+	}
+	result.Passed = strings.Contains(logs, "Test PASSED")
+	if !result.Passed {
+		result.Message = "vectorAdd did not report 'Test PASSED'"
+	}
+	return result
+}
@@ -0,0 +1,304 @@
+package workloads
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	GpuResource      = "nvidia.com/gpu"
+	ModeDaemonSet    = "daemonset"
+	ModeJob          = "job"
+	NodeAnnotation   = "ci.nvidia.com/node"
+	ExitCodeMarker   = "workload exit code:"
+	entrypointKey    = "entrypoint.sh"
+	entrypointPath   = "/bin/entrypoint.sh"
+	entrypointVolume = "entrypoint"
+)
+
+var (
+	no             bool = false
+	yes            bool = true
+	exitCodeRegex       = regexp.MustCompile(ExitCodeMarker + ` (\d+)`)
+	defaultOptions      = Options{Duration: 300 * time.Second}
+)
+
+// Workload describes a GPU workload that can be deployed on every GPU node
+// and whose logs can be turned into a structured Result.
+type Workload interface {
+	// Name is used as the app label and as the prefix of all created objects.
+	Name() string
+	Image() string
+	// Entrypoint is the bash script mounted as the container command. It
+	// exits with a non zero code when the workload fails.
+	Entrypoint() string
+	// Parse turns the logs of a single finished run into a Result.
+	Parse(logs string) Result
+}
+
+// ComputeCapabilityRequirer is implemented by the workloads that need GPUs of
+// at least a compute capability, the specs skip them on older GPUs.
+type ComputeCapabilityRequirer interface {
+	// MinComputeCapability returns the major and minor compute capability.
+	MinComputeCapability() (int, int)
+}
+
+// Options tune a workload. Zero values keep the workload defaults.
+type Options struct {
+	Image string
+	// Duration is the run time of workloads that run for a fixed time, like gpu-burn.
+	Duration time.Duration
+}
+
+// Result is the outcome of a workload run on a single node.
+type Result struct {
+	Workload   string  `json:"workload"`
+	Node       string  `json:"node"`
+	Pod        string  `json:"pod"`
+	Passed     bool    `json:"passed"`
+	Throughput float64 `json:"throughput,omitempty"`
+	Unit       string  `json:"unit,omitempty"`
+	Message    string  `json:"message,omitempty"`
+}
+
+type factory func(opts Options) Workload
+
+var registry = map[string]factory{
+	GpuBurnName:   func(opts Options) Workload { return NewGpuBurn(opts.Image, opts.Duration) },
+	vectorAddName: func(opts Options) Workload { return NewVectorAdd(opts.Image) },
+	ncclTestsName: func(opts Options) Workload { return NewNcclTests(opts.Image) },
+	dcgmDiagName:  func(opts Options) Workload { return NewDcgmDiag(opts.Image) },
+	pytorchName:   func(opts Options) Workload { return NewPytorch(opts.Image) },
+}
+
+// Get returns the workload registered under name.
+func Get(name string, opts Options) (Workload, error) {
+	f, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown workload %q, expected one of %v", name, Names())
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = defaultOptions.Duration
+	}
+	return f(opts), nil
+}
+
+func Names() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Labels(w Workload) map[string]string {
+	return map[string]string{
+		"app": w.Name(),
+	}
+}
+
+func LabelSelector(w Workload) string {
+	return fmt.Sprintf("app=%v", w.Name())
+}
+
+func EntrypointConfigMapName(w Workload) string {
+	return fmt.Sprintf("%v-entrypoint", w.Name())
+}
+
+func NewConfigMap(namespace string, w Workload) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      EntrypointConfigMapName(w),
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			entrypointKey: w.Entrypoint(),
+		},
+	}
+}
+
+// NewDaemonSet keeps the container running once the workload is over, so the
+// pods do not restart and overwrite the logs. The workload exit code is
+// printed in the logs and can be read with ExitCodeFromLogs.
+func NewDaemonSet(namespace string, w Workload, gpus int64) *appsv1.DaemonSet {
+	template := NewPodTemplate(w, gpus)
+	template.Spec.Containers[0].Command = []string{
+		"/bin/bash",
+		"-c",
+		fmt.Sprintf("%v; echo \"%v $?\"; sleep infinity", entrypointPath, ExitCodeMarker),
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      w.Name(),
+			Namespace: namespace,
+			Labels:    Labels(w),
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: Labels(w),
+			},
+			Template: template,
+		},
+	}
+}
+
+// NewJob returns a Job running the workload once on node, with all of its gpus.
+// The Job is terminated when it runs for longer than deadline.
+func NewJob(namespace string, w Workload, index int, node string, gpus int64, deadline time.Duration) *batchv1.Job {
+	var backoffLimit int32 = 0
+	deadlineSeconds := int64(deadline.Seconds())
+	template := NewPodTemplate(w, gpus)
+	template.ObjectMeta.Annotations = map[string]string{
+		NodeAnnotation: node,
+	}
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	template.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchFields: []corev1.NodeSelectorRequirement{
+							{
+								Key:      "metadata.name",
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   []string{node},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			// Node names are often longer than the 63 characters allowed in the job-name label
+			Name:      fmt.Sprintf("%v-%d", w.Name(), index),
+			Namespace: namespace,
+			Labels:    Labels(w),
+			Annotations: map[string]string{
+				NodeAnnotation: node,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadlineSeconds,
+			Template:              template,
+		},
+	}
+}
+
+// NewPodTemplate returns the pod template shared by all the workloads: a single
+// restricted container running the workload entrypoint on gpus GPUs of a GPU worker node.
+func NewPodTemplate(w Workload, gpus int64) corev1.PodTemplateSpec {
+	var volumeDefaultMode int32 = 0777
+	configMapVolumeSource := &corev1.ConfigMapVolumeSource{}
+	configMapVolumeSource.Name = EntrypointConfigMapName(w)
+	configMapVolumeSource.DefaultMode = &volumeDefaultMode
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: Labels(w),
+		},
+		Spec: corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: &yes,
+			},
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+				{
+					Key:      GpuResource,
+					Effect:   corev1.TaintEffectNoSchedule,
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Image:           w.Image(),
+					ImagePullPolicy: corev1.PullAlways,
+					SecurityContext: &corev1.SecurityContext{
+						RunAsNonRoot: &yes,
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+						AllowPrivilegeEscalation: &no,
+						Capabilities: &corev1.Capabilities{
+							Drop: []corev1.Capability{
+								"ALL",
+							},
+						},
+					},
+					Name: fmt.Sprintf("%v-ctr", w.Name()),
+					Command: []string{
+						entrypointPath,
+					},
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							GpuResource: *resource.NewQuantity(gpus, resource.DecimalSI),
+						},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      entrypointVolume,
+							MountPath: entrypointPath,
+							ReadOnly:  true,
+							SubPath:   entrypointKey,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: entrypointVolume,
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: configMapVolumeSource,
+					},
+				},
+			},
+			NodeSelector: map[string]string{
+				"nvidia.com/gpu.present":         "true",
+				"node-role.kubernetes.io/worker": "",
+			},
+		},
+	}
+}
+
+// RequestedGpus returns the GPUs requested by the containers of a workload
+// pod, which may be fewer than the GPU capacity of its node.
+func RequestedGpus(pod corev1.Pod) int64 {
+	var gpus int64
+	for _, container := range pod.Spec.Containers {
+		if limit, ok := container.Resources.Limits[GpuResource]; ok {
+			gpus += limit.Value()
+		}
+	}
+	return gpus
+}
+
+// ExitCodeFromLogs returns the workload exit code printed by the DaemonSet
+// pods, the second value is false while the workload is still running.
+func ExitCodeFromLogs(logs string) (int, bool) {
+	match := exitCodeRegex.FindStringSubmatch(logs)
+	if match == nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(match[1])
+	return code, err == nil
+}
+
+func imageOrDefault(image string, _default string) string {
+	if len(image) > 0 {
+		return image
+	}
+	return _default
+}
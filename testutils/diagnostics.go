@@ -0,0 +1,173 @@
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	gpuv1 "github.com/NVIDIA/gpu-operator/api/v1"
+	ginkgo "github.com/onsi/ginkgo/v2"
+	nfdv1 "github.com/openshift/cluster-nfd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/internal"
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils/inventory"
+	"ci-tools-nvidia-gpu-operator/testutils/report"
+)
+
+var diagnosticsNamespaces = []string{}
+
+// AddDiagnosticsNamespace adds a namespace created by a test to the
+// namespaces collected on failure, on top of the operator namespace.
+func AddDiagnosticsNamespace(namespace string) {
+	for _, ns := range diagnosticsNamespaces {
+		if ns == namespace {
+			return
+		}
+	}
+	diagnosticsNamespaces = append(diagnosticsNamespaces, namespace)
+}
+
+// CollectDiagnosticsOnFailure registers a ReportAfterEach node collecting the
+// cluster state into <artifact dir>/diagnostics/<spec> when a spec fails. It
+// is meant to be called once per suite:
+//
+//	var _ = testutils.CollectDiagnosticsOnFailure()
+func CollectDiagnosticsOnFailure() bool {
+	return ginkgo.ReportAfterEach(func(specReport ginkgo.SpecReport) {
+		if !specReport.Failed() {
+			return
+		}
+		dir := path.Join(internal.Config.ArtifactDir, report.DiagnosticsDir, report.DiagnosticsDirName(specReport.FullText()))
+		fmt.Printf("[Diagnostics]: spec failed, collecting diagnostics into %v\n", dir)
+		collectDiagnostics(internal.GetClientConfig(), dir)
+	})
+}
+
+// diagnostics writes into dir and records the errors instead of failing, so
+// that a missing resource does not prevent collecting the others.
+type diagnostics struct {
+	dir    string
+	errors []string
+}
+
+func (d *diagnostics) check(err error, what string) bool {
+	if err == nil {
+		return true
+	}
+	d.errors = append(d.errors, fmt.Sprintf("%v: %v", what, err))
+	return false
+}
+
+func (d *diagnostics) save(data []byte, filename string) {
+	filepath := path.Join(d.dir, filename)
+	if !d.check(os.MkdirAll(path.Dir(filepath), 0755), filepath) {
+		return
+	}
+	d.check(os.WriteFile(filepath, data, 0644), filepath)
+}
+
+func (d *diagnostics) saveJson(obj interface{}, filename string) {
+	jsn, err := json.MarshalIndent(obj, "", " ")
+	if d.check(err, filename) {
+		d.save(jsn, filename)
+	}
+}
+
+func collectDiagnostics(config *rest.Config, dir string) {
+	d := &diagnostics{dir: dir}
+	collected := map[string]bool{}
+	for _, namespace := range append([]string{internal.Config.NameSpace}, diagnosticsNamespaces...) {
+		if collected[namespace] {
+			continue
+		}
+		collected[namespace] = true
+		d.collectNamespace(config, namespace)
+	}
+	clusterPolicies, err := ocputils.ListDynamicResource(config, gpuv1.GroupVersion.WithResource("clusterpolicies"))
+	if d.check(err, "clusterpolicies") {
+		d.saveJson(clusterPolicies.Items, "clusterpolicies.json")
+	}
+	nfds, err := ocputils.ListDynamicResource(config, nfdv1.GroupVersion.WithResource("nodefeaturediscoveries"))
+	if d.check(err, "nodefeaturediscoveries") {
+		d.saveJson(nfds.Items, "nodefeaturediscoveries.json")
+	}
+	d.collectNodes(config)
+	if len(d.errors) > 0 {
+		d.save([]byte(strings.Join(d.errors, "\n")+"\n"), "errors.txt")
+	}
+}
+
+// collectNamespace saves the events, the pods and the logs of all the
+// containers of the pods, including init and previous containers.
+func (d *diagnostics) collectNamespace(config *rest.Config, namespace string) {
+	events, err := ocputils.GetEventsByFieldSelector(config, namespace, "")
+	if d.check(err, fmt.Sprintf("events of %v", namespace)) {
+		d.saveJson(events.Items, path.Join(namespace, "events.json"))
+	}
+	pods, err := ocputils.GetPodsByLabel(config, namespace, "")
+	if !d.check(err, fmt.Sprintf("pods of %v", namespace)) {
+		return
+	}
+	d.saveJson(pods.Items, path.Join(namespace, "pods.json"))
+	for _, pod := range pods.Items {
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			d.collectContainerLogs(config, pod, status, false)
+			if status.RestartCount > 0 {
+				d.collectContainerLogs(config, pod, status, true)
+			}
+		}
+	}
+}
+
+func (d *diagnostics) collectContainerLogs(config *rest.Config, pod corev1.Pod, status corev1.ContainerStatus, previous bool) {
+	filename := fmt.Sprintf("%v_%v.log", pod.Name, status.Name)
+	if previous {
+		filename = fmt.Sprintf("%v_%v_previous.log", pod.Name, status.Name)
+	}
+	logs, err := ocputils.GetContainerLogs(config, pod, status.Name, previous)
+	if d.check(err, fmt.Sprintf("logs of %v/%v container %v", pod.Namespace, pod.Name, status.Name)) {
+		d.save([]byte(*logs), path.Join(pod.Namespace, "logs", filename))
+	}
+}
+
+type nodeDiagnostics struct {
+	Name          string                 `json:"name"`
+	Unschedulable bool                   `json:"unschedulable,omitempty"`
+	Taints        []corev1.Taint         `json:"taints,omitempty"`
+	Capacity      corev1.ResourceList    `json:"capacity"`
+	Allocatable   corev1.ResourceList    `json:"allocatable"`
+	Conditions    []corev1.NodeCondition `json:"conditions"`
+}
+
+func (d *diagnostics) collectNodes(config *rest.Config) {
+	nodes, err := ocputils.GetNodesByLabel(config, "")
+	if !d.check(err, "nodes") {
+		return
+	}
+	nodeDiags := []nodeDiagnostics{}
+	for _, node := range nodes.Items {
+		nodeDiags = append(nodeDiags, nodeDiagnostics{
+			Name:          node.Name,
+			Unschedulable: node.Spec.Unschedulable,
+			Taints:        node.Spec.Taints,
+			Capacity:      node.Status.Capacity,
+			Allocatable:   node.Status.Allocatable,
+			Conditions:    node.Status.Conditions,
+		})
+	}
+	d.saveJson(nodeDiags, "nodes.json")
+	inv := inventory.Build(nodes.Items)
+	d.saveJson(inv, inventory.InventoryFile)
+	table := new(bytes.Buffer)
+	if d.check(inv.WriteTable(table), "GPU inventory") {
+		d.save(table.Bytes(), inventory.InventoryTableFile)
+	}
+}
@@ -0,0 +1,238 @@
+package testutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	eventsTimelineFile = "events-timeline"
+	// Node events are recorded in the default namespace
+	nodeEventsNamespace = "default"
+	rewatchInterval     = 5 * time.Second
+)
+
+// TimelineEvent is an event of the timeline. Events repeated for the same
+// object, with the same reason and message, are merged into one.
+type TimelineEvent struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Namespace string    `json:"namespace,omitempty"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count"`
+	// counts of the merged events by UID, to not count an update twice
+	counts map[string]int32
+}
+
+// EventRecorder watches events in the background until Stop is called, the
+// events last seen before it started are left out.
+type EventRecorder struct {
+	clientset *kubernetes.Clientset
+	start     time.Time
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	events    map[string]*TimelineEvent
+}
+
+// StartEventRecorder watches the events of namespaces, see
+// internal.EventNamespaces, and the node events.
+func StartEventRecorder(config *rest.Config, namespaces []string) (*EventRecorder, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newEventRecorder(time.Now().Truncate(time.Second))
+	r.clientset = clientset
+	r.cancel = cancel
+	for _, namespace := range namespaces {
+		r.run(ctx, namespace, "")
+	}
+	r.run(ctx, nodeEventsNamespace, "involvedObject.kind=Node")
+	return r, nil
+}
+
+func newEventRecorder(start time.Time) *EventRecorder {
+	return &EventRecorder{
+		start:  start,
+		events: map[string]*TimelineEvent{},
+	}
+}
+
+func (r *EventRecorder) run(ctx context.Context, namespace string, fieldSelector string) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.watch(ctx, namespace, fieldSelector)
+	}()
+}
+
+// watch lists the events then watches from the list resource version, and
+// restarts when the API server closes the watch, from the last seen resource
+// version, or with a new list when it expired.
+func (r *EventRecorder) watch(ctx context.Context, namespace string, fieldSelector string) {
+	resourceVersion := ""
+	for ctx.Err() == nil {
+		if len(resourceVersion) == 0 {
+			list, err := r.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: fieldSelector})
+			if err != nil {
+				sleepOrDone(ctx, rewatchInterval)
+				continue
+			}
+			for i := range list.Items {
+				r.record(&list.Items[i])
+			}
+			resourceVersion = list.ResourceVersion
+		}
+		w, err := r.clientset.CoreV1().Events(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fieldSelector,
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			resourceVersion = ""
+			sleepOrDone(ctx, rewatchInterval)
+			continue
+		}
+		for result := range w.ResultChan() {
+			if result.Type == watch.Error {
+				// Most likely an expired resource version
+				resourceVersion = ""
+				break
+			}
+			event, ok := result.Object.(*corev1.Event)
+			if !ok || result.Type == watch.Deleted {
+				continue
+			}
+			resourceVersion = event.ResourceVersion
+			r.record(event)
+		}
+		w.Stop()
+		sleepOrDone(ctx, rewatchInterval)
+	}
+}
+
+func sleepOrDone(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func eventTime(event *corev1.Event) (time.Time, time.Time) {
+	first, last := event.FirstTimestamp.Time, event.LastTimestamp.Time
+	if first.IsZero() {
+		first = event.EventTime.Time
+	}
+	if event.Series != nil {
+		last = event.Series.LastObservedTime.Time
+	}
+	if first.IsZero() {
+		first = event.CreationTimestamp.Time
+	}
+	if last.IsZero() {
+		last = first
+	}
+	return first, last
+}
+
+func (r *EventRecorder) record(event *corev1.Event) {
+	obj := event.InvolvedObject
+	key := fmt.Sprintf("%v/%v/%v/%v/%v", obj.Namespace, obj.Kind, obj.Name, event.Reason, event.Message)
+	first, last := eventTime(event)
+	if last.Before(r.start) {
+		return
+	}
+	count := event.Count
+	if event.Series != nil {
+		count = event.Series.Count
+	}
+	if count == 0 {
+		count = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.events[key]
+	if !ok {
+		e = &TimelineEvent{
+			FirstSeen: first,
+			LastSeen:  last,
+			Namespace: obj.Namespace,
+			Kind:      obj.Kind,
+			Name:      obj.Name,
+			Type:      event.Type,
+			Reason:    event.Reason,
+			Message:   event.Message,
+			counts:    map[string]int32{},
+		}
+		r.events[key] = e
+	}
+	if first.Before(e.FirstSeen) {
+		e.FirstSeen = first
+	}
+	if last.After(e.LastSeen) {
+		e.LastSeen = last
+	}
+	e.Count += count - e.counts[string(event.UID)]
+	e.counts[string(event.UID)] = count
+}
+
+// Timeline returns the recorded events in chronological order, sorted by the
+// time they were first seen.
+func (r *EventRecorder) Timeline() []TimelineEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timeline := []TimelineEvent{}
+	for _, e := range r.events {
+		timeline = append(timeline, *e)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		if timeline[i].FirstSeen.Equal(timeline[j].FirstSeen) {
+			return timeline[i].LastSeen.Before(timeline[j].LastSeen)
+		}
+		return timeline[i].FirstSeen.Before(timeline[j].FirstSeen)
+	})
+	return timeline
+}
+
+// Stop stops watching and writes the timeline to the artifacts dir, as JSON
+// and as text with one line per event.
+func (r *EventRecorder) Stop() error {
+	r.cancel()
+	r.wg.Wait()
+	timeline := r.Timeline()
+	if err := SaveAsJsonToArtifactsDir(timeline, eventsTimelineFile+".json"); err != nil {
+		return err
+	}
+	return SaveToArtifactsDir(FormatTimeline(timeline), eventsTimelineFile+".txt")
+}
+
+func FormatTimeline(timeline []TimelineEvent) []byte {
+	buf := new(bytes.Buffer)
+	for _, e := range timeline {
+		object := fmt.Sprintf("%v/%v", e.Kind, e.Name)
+		if len(e.Namespace) > 0 {
+			object = fmt.Sprintf("%v/%v/%v", e.Kind, e.Namespace, e.Name)
+		}
+		fmt.Fprintf(buf, "%v %-7v %v %v", e.FirstSeen.UTC().Format(time.RFC3339), e.Type, e.Reason, object)
+		if e.Count > 1 {
+			fmt.Fprintf(buf, " (x%v until %v)", e.Count, e.LastSeen.UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintf(buf, ": %v\n", e.Message)
+	}
+	return buf.Bytes()
+}
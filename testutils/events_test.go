@@ -0,0 +1,67 @@
+package testutils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestEvent(uid string, name string, reason string, first time.Time, last time.Time, count int32) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid)},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: "gpu-burn-test",
+			Name:      name,
+		},
+		Type:           corev1.EventTypeNormal,
+		Reason:         reason,
+		Message:        reason + " " + name,
+		FirstTimestamp: metav1.NewTime(first),
+		LastTimestamp:  metav1.NewTime(last),
+		Count:          count,
+	}
+}
+
+func TestEventRecorderRecord(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r := newEventRecorder(start)
+
+	// Before the recorder started
+	r.record(newTestEvent("old", "gpu-burn-a", "Pulled", start.Add(-time.Hour), start.Add(-time.Minute), 1))
+	// An update of the same event is not counted twice
+	r.record(newTestEvent("1", "gpu-burn-a", "BackOff", start.Add(time.Minute), start.Add(2*time.Minute), 2))
+	r.record(newTestEvent("1", "gpu-burn-a", "BackOff", start.Add(time.Minute), start.Add(5*time.Minute), 4))
+	// Another event of the same object, reason and message is merged
+	r.record(newTestEvent("2", "gpu-burn-a", "BackOff", start.Add(6*time.Minute), start.Add(6*time.Minute), 1))
+	// Last seen first but first seen last
+	r.record(newTestEvent("3", "gpu-burn-b", "Scheduled", start.Add(3*time.Minute), start.Add(3*time.Minute), 0))
+
+	timeline := r.Timeline()
+	if len(timeline) != 2 {
+		t.Fatalf("expected 2 events, got %+v", timeline)
+	}
+	backOff, scheduled := timeline[0], timeline[1]
+	if backOff.Reason != "BackOff" || scheduled.Reason != "Scheduled" {
+		t.Fatalf("timeline is not sorted by first seen: %+v", timeline)
+	}
+	if backOff.Count != 5 {
+		t.Errorf("expected 5 BackOff events, got %v", backOff.Count)
+	}
+	if !backOff.FirstSeen.Equal(start.Add(time.Minute)) || !backOff.LastSeen.Equal(start.Add(6*time.Minute)) {
+		t.Errorf("unexpected BackOff times %v %v", backOff.FirstSeen, backOff.LastSeen)
+	}
+	if scheduled.Count != 1 {
+		t.Errorf("an event without count happened once, got %v", scheduled.Count)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(FormatTimeline(timeline))), "\n")
+	expected := "2024-05-01T10:01:00Z Normal  BackOff Pod/gpu-burn-test/gpu-burn-a (x5 until 2024-05-01T10:06:00Z): BackOff gpu-burn-a"
+	if len(lines) != 2 || lines[0] != expected {
+		t.Errorf("unexpected timeline:\n%v", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,32 @@
+package testutils
+
+import (
+	"bytes"
+
+	"k8s.io/client-go/rest"
+
+	"ci-tools-nvidia-gpu-operator/ocputils"
+	"ci-tools-nvidia-gpu-operator/testutils/inventory"
+)
+
+// GetGpuInventory builds the GPU inventory of the cluster nodes, specs skip
+// on the GPU models or capabilities they need with it.
+func GetGpuInventory(config *rest.Config) (*inventory.Inventory, error) {
+	nodes, err := ocputils.GetNodesByLabel(config, "")
+	if err != nil {
+		return nil, err
+	}
+	return inventory.Build(nodes.Items), nil
+}
+
+// SaveGpuInventory saves the inventory as JSON and as a table.
+func SaveGpuInventory(inv *inventory.Inventory) error {
+	if err := SaveAsJsonToArtifactsDir(inv, inventory.InventoryFile); err != nil {
+		return err
+	}
+	table := new(bytes.Buffer)
+	if err := inv.WriteTable(table); err != nil {
+		return err
+	}
+	return SaveToArtifactsDir(table.Bytes(), inventory.InventoryTableFile)
+}
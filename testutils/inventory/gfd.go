@@ -0,0 +1,163 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SmiQueryScript prints the GPUs as seen by nvidia-smi, parsed by ParseSmiOutput.
+const SmiQueryScript = `#!/bin/bash
+set -e
+nvidia-smi --query-gpu=name,memory.total,pci.device_id,driver_version --format=csv,noheader,nounits
+nvidia-smi | grep -o 'CUDA Version: [0-9.]*'`
+
+const (
+	cudaVersionPrefix = "CUDA Version:"
+	// GFD appends it to the product of shared GPUs
+	sharedProductSuffix = "-SHARED"
+)
+
+// SmiGpu is a GPU listed by nvidia-smi.
+type SmiGpu struct {
+	Name          string `json:"name"`
+	MemoryMiB     int64  `json:"memory_mib"`
+	PciDevice     string `json:"pci_device"`
+	DriverVersion string `json:"driver_version"`
+}
+
+// SmiOutput is the output of SmiQueryScript.
+type SmiOutput struct {
+	Gpus        []SmiGpu `json:"gpus"`
+	CudaVersion string   `json:"cuda_version"`
+}
+
+// ParseSmiOutput parses the output of SmiQueryScript.
+func ParseSmiOutput(output string) (*SmiOutput, error) {
+	smi := &SmiOutput{Gpus: []SmiGpu{}}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if version, ok := strings.CutPrefix(line, cudaVersionPrefix); ok {
+			smi.CudaVersion = strings.TrimSpace(version)
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 4 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		memory, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU memory in %q: %v", line, err)
+		}
+		smi.Gpus = append(smi.Gpus, SmiGpu{
+			Name:          fields[0],
+			MemoryMiB:     memory,
+			PciDevice:     pciDeviceID(fields[2]),
+			DriverVersion: fields[3],
+		})
+	}
+	if len(smi.Gpus) == 0 {
+		return nil, fmt.Errorf("no GPU in the nvidia-smi output")
+	}
+	return smi, nil
+}
+
+// pciDeviceID returns the device of the nvidia-smi pci.device_id, e.g. 1eb8
+// for 0x1EB810DE, the vendor is in the low 16 bits.
+func pciDeviceID(id string) string {
+	id = strings.ToLower(strings.TrimPrefix(strings.ToLower(id), "0x"))
+	if len(id) == 8 {
+		return id[:4]
+	}
+	return id
+}
+
+// Product returns the GFD product of the GPU name.
+func (g SmiGpu) Product() string {
+	return strings.ReplaceAll(g.Name, " ", "-")
+}
+
+// MigEnabled returns whether GFD labels the node with MIG devices, not whole GPUs.
+func (n NodeGpus) MigEnabled() bool {
+	return n.MigStrategy == "mixed" || strings.Contains(n.Product, "-MIG-")
+}
+
+// Shared returns whether the node GPUs are shared, e.g. time-sliced.
+func (n NodeGpus) Shared() bool {
+	return len(n.SharingStrategy) > 0 && n.SharingStrategy != "none"
+}
+
+// CheckGfdLabels returns the inconsistencies between the GFD labels of the
+// node, its GPU capacity, its NFD PCI devices, and nvidia-smi when not nil.
+// nvidia-smi must see all the GPUs of the node, as in the driver container.
+func CheckGfdLabels(n NodeGpus, smi *SmiOutput) []string {
+	errs := []string{}
+	if !n.GfdLabeled {
+		return []string{fmt.Sprintf("%v has no %v label", n.Node, LabelGpuProduct)}
+	}
+	if !n.NfdDetected() {
+		errs = append(errs, "GFD labeled the node but NFD found no NVIDIA PCI device")
+	}
+	if n.Count == 0 {
+		errs = append(errs, fmt.Sprintf("%v is missing or 0", LabelGpuCount))
+	}
+	expectedCapacity := n.Count
+	if n.Shared() && n.Replicas > 0 {
+		expectedCapacity = n.Count * n.Replicas
+	}
+	if !n.MigEnabled() && n.Capacity != expectedCapacity {
+		errs = append(errs, fmt.Sprintf("%v capacity is %v, expected %v from %v=%v", GpuResource, n.Capacity, expectedCapacity, LabelGpuCount, n.Count))
+	}
+	if smi == nil {
+		return errs
+	}
+
+	driverMajor, _, _ := strings.Cut(n.DriverVersion, ".")
+	cudaMajor, _, _ := strings.Cut(n.CudaVersion, ".")
+	smiCudaMajor, _, _ := strings.Cut(smi.CudaVersion, ".")
+	if len(cudaMajor) == 0 || cudaMajor != smiCudaMajor {
+		errs = append(errs, fmt.Sprintf("%v is %q, nvidia-smi CUDA version is %v", LabelCudaRuntimeMajor, cudaMajor, smi.CudaVersion))
+	}
+	devices := map[string]bool{}
+	for _, gpu := range smi.Gpus {
+		devices[gpu.PciDevice] = true
+		smiDriverMajor, _, _ := strings.Cut(gpu.DriverVersion, ".")
+		if len(driverMajor) == 0 || driverMajor != smiDriverMajor {
+			errs = append(errs, fmt.Sprintf("%v is %q, nvidia-smi driver version is %v", LabelCudaDriverMajor, driverMajor, gpu.DriverVersion))
+		}
+		if n.MigEnabled() {
+			continue
+		}
+		if product := strings.TrimSuffix(n.Product, sharedProductSuffix); product != gpu.Product() {
+			errs = append(errs, fmt.Sprintf("%v is %v, nvidia-smi GPU is %v", LabelGpuProduct, n.Product, gpu.Name))
+		}
+		if n.MemoryMiB != gpu.MemoryMiB {
+			errs = append(errs, fmt.Sprintf("%v is %v, nvidia-smi %v memory is %vMiB", LabelGpuMemory, n.MemoryMiB, gpu.Name, gpu.MemoryMiB))
+		}
+	}
+	if !n.MigEnabled() && int64(len(smi.Gpus)) != n.Count {
+		errs = append(errs, fmt.Sprintf("%v is %v, nvidia-smi lists %v GPUs", LabelGpuCount, n.Count, len(smi.Gpus)))
+	}
+	// NFD has the device IDs when they are in its deviceLabelFields
+	for _, d := range n.PciDevices {
+		if len(d.Device) > 0 && !devices[d.Device] {
+			errs = append(errs, fmt.Sprintf("NFD PCI device %v is not listed by nvidia-smi", d))
+		}
+	}
+	sort.Strings(errs)
+	return dedup(errs)
+}
+
+func dedup(sorted []string) []string {
+	unique := []string{}
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
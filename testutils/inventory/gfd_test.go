@@ -0,0 +1,108 @@
+package inventory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const smiOutput = `NVIDIA A100-SXM4-40GB, 40960, 0x20B010DE, 535.104.05
+NVIDIA A100-SXM4-40GB, 40960, 0x20B010DE, 535.104.05
+CUDA Version: 12.2
+`
+
+func a100Node() NodeGpus {
+	return NodeGpus{
+		Node:          "worker-a",
+		PciDevices:    []PciDevice{{Class: "0302", Vendor: NvidiaVendorID, Device: "20b0"}},
+		GfdLabeled:    true,
+		Product:       "NVIDIA-A100-SXM4-40GB",
+		Count:         2,
+		MemoryMiB:     40960,
+		DriverVersion: "535.104.05",
+		CudaVersion:   "12.2",
+		Capacity:      2,
+	}
+}
+
+func TestParseSmiOutput(t *testing.T) {
+	smi, err := ParseSmiOutput(smiOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := SmiGpu{Name: "NVIDIA A100-SXM4-40GB", MemoryMiB: 40960, PciDevice: "20b0", DriverVersion: "535.104.05"}
+	if len(smi.Gpus) != 2 || !reflect.DeepEqual(smi.Gpus[0], expected) {
+		t.Errorf("expected 2 x %+v, got %+v", expected, smi.Gpus)
+	}
+	if smi.CudaVersion != "12.2" {
+		t.Errorf("expected CUDA version 12.2, got %v", smi.CudaVersion)
+	}
+	if smi.Gpus[0].Product() != "NVIDIA-A100-SXM4-40GB" {
+		t.Errorf("unexpected product %v", smi.Gpus[0].Product())
+	}
+	if _, err := ParseSmiOutput("No devices were found\n"); err == nil {
+		t.Errorf("expected an error without GPU")
+	}
+}
+
+func TestCheckGfdLabels(t *testing.T) {
+	smi, err := ParseSmiOutput(smiOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := CheckGfdLabels(a100Node(), smi); len(errs) != 0 {
+		t.Errorf("expected consistent labels, got %v", errs)
+	}
+
+	tests := []struct {
+		name     string
+		update   func(n *NodeGpus)
+		expected string
+	}{
+		{"count", func(n *NodeGpus) { n.Count = 1 }, "capacity is 2, expected 1"},
+		{"listed", func(n *NodeGpus) { n.Count = 4; n.Capacity = 4 }, "nvidia-smi lists 2 GPUs"},
+		{"product", func(n *NodeGpus) { n.Product = "Tesla-T4" }, "nvidia-smi GPU is NVIDIA A100-SXM4-40GB"},
+		{"memory", func(n *NodeGpus) { n.MemoryMiB = 81920 }, "memory is 40960MiB"},
+		{"driver", func(n *NodeGpus) { n.DriverVersion = "550.54" }, "nvidia-smi driver version is 535.104.05"},
+		{"cuda", func(n *NodeGpus) { n.CudaVersion = "11.8" }, "nvidia-smi CUDA version is 12.2"},
+		{"nfd", func(n *NodeGpus) { n.PciDevices = nil }, "NFD found no NVIDIA PCI device"},
+		{"pci", func(n *NodeGpus) { n.PciDevices[0].Device = "1eb8" }, "NFD PCI device 0302_10de_1eb8"},
+		{"gfd", func(n *NodeGpus) { n.GfdLabeled = false }, "has no nvidia.com/gpu.product label"},
+	}
+	for _, test := range tests {
+		n := a100Node()
+		n.PciDevices = append([]PciDevice{}, n.PciDevices...)
+		test.update(&n)
+		errs := CheckGfdLabels(n, smi)
+		if !strings.Contains(strings.Join(errs, "\n"), test.expected) {
+			t.Errorf("%v: expected an error with %q, got %v", test.name, test.expected, errs)
+		}
+	}
+}
+
+func TestCheckGfdLabelsShared(t *testing.T) {
+	smi, err := ParseSmiOutput("Tesla T4, 15360, 0x1EB810DE, 535.104.05\nTesla T4, 15360, 0x1EB810DE, 535.104.05\nCUDA Version: 12.2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NodeGpus{
+		Node:            "worker-b",
+		PciDevices:      []PciDevice{{Class: "0302", Vendor: NvidiaVendorID}},
+		GfdLabeled:      true,
+		Product:         "Tesla-T4-SHARED",
+		Count:           2,
+		MemoryMiB:       15360,
+		DriverVersion:   "535.104.05",
+		CudaVersion:     "12.2",
+		SharingStrategy: "time-slicing",
+		Replicas:        4,
+		Capacity:        8,
+	}
+	if errs := CheckGfdLabels(n, smi); len(errs) != 0 {
+		t.Errorf("expected consistent labels, got %v", errs)
+	}
+	n.Capacity = 2
+	if errs := CheckGfdLabels(n, smi); len(errs) != 1 {
+		t.Errorf("expected a capacity error, got %v", errs)
+	}
+}
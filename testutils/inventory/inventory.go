@@ -0,0 +1,321 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	InventoryFile      = "gpu_inventory.json"
+	InventoryTableFile = "gpu_inventory.txt"
+
+	NvidiaVendorID = "10de"
+	GpuResource    = "nvidia.com/gpu"
+
+	// NFD labels PCI devices feature.node.kubernetes.io/pci-<fields>.present,
+	// the fields are <class>_<vendor> by default, or <vendor> and
+	// <class>_<vendor>_<device> depending on the NFD deviceLabelFields.
+	nfdPciPrefix  = "feature.node.kubernetes.io/pci-"
+	nfdPciPresent = ".present"
+)
+
+// GPU Feature Discovery labels
+const (
+	LabelGpuPresent         = "nvidia.com/gpu.present"
+	LabelGpuProduct         = "nvidia.com/gpu.product"
+	LabelGpuCount           = "nvidia.com/gpu.count"
+	LabelGpuMemory          = "nvidia.com/gpu.memory"
+	LabelGpuFamily          = "nvidia.com/gpu.family"
+	LabelGpuComputeMajor    = "nvidia.com/gpu.compute.major"
+	LabelGpuComputeMinor    = "nvidia.com/gpu.compute.minor"
+	LabelGpuMachine         = "nvidia.com/gpu.machine"
+	LabelGpuSharingStrategy = "nvidia.com/gpu.sharing-strategy"
+	LabelGpuReplicas        = "nvidia.com/gpu.replicas"
+	LabelCudaDriverMajor    = "nvidia.com/cuda.driver.major"
+	LabelCudaDriverMinor    = "nvidia.com/cuda.driver.minor"
+	LabelCudaDriverRev      = "nvidia.com/cuda.driver.rev"
+	LabelCudaRuntimeMajor   = "nvidia.com/cuda.runtime.major"
+	LabelCudaRuntimeMinor   = "nvidia.com/cuda.runtime.minor"
+	LabelMigCapable         = "nvidia.com/mig.capable"
+	LabelMigStrategy        = "nvidia.com/mig.strategy"
+)
+
+// Newer GFD names of the CUDA labels, older GFD versions publish the original
+// names only
+var gfdLabelAliases = map[string]string{
+	LabelCudaDriverMajor:  "nvidia.com/cuda.driver-version.major",
+	LabelCudaDriverMinor:  "nvidia.com/cuda.driver-version.minor",
+	LabelCudaDriverRev:    "nvidia.com/cuda.driver-version.revision",
+	LabelCudaRuntimeMajor: "nvidia.com/cuda.runtime-version.major",
+	LabelCudaRuntimeMinor: "nvidia.com/cuda.runtime-version.minor",
+}
+
+// PciDevice is an NVIDIA PCI device labeled by NFD, Class and Device are
+// empty when not in the NFD label fields.
+type PciDevice struct {
+	Class  string `json:"class,omitempty"`
+	Vendor string `json:"vendor"`
+	Device string `json:"device,omitempty"`
+}
+
+func (d PciDevice) String() string {
+	fields := []string{}
+	for _, f := range []string{d.Class, d.Vendor, d.Device} {
+		if len(f) > 0 {
+			fields = append(fields, f)
+		}
+	}
+	return strings.Join(fields, "_")
+}
+
+// NodeGpus is the GPU inventory of a node. The GFD fields are empty until
+// GPU Feature Discovery labels the node.
+type NodeGpus struct {
+	Node       string      `json:"node"`
+	PciDevices []PciDevice `json:"pci_devices,omitempty"`
+	// GfdLabeled when GPU Feature Discovery labeled the node
+	GfdLabeled      bool   `json:"gfd_labeled"`
+	Product         string `json:"product,omitempty"`
+	Family          string `json:"family,omitempty"`
+	Machine         string `json:"machine,omitempty"`
+	Count           int64  `json:"count,omitempty"`
+	MemoryMiB       int64  `json:"memory_mib,omitempty"`
+	Compute         string `json:"compute,omitempty"`
+	DriverVersion   string `json:"driver_version,omitempty"`
+	CudaVersion     string `json:"cuda_version,omitempty"`
+	MigCapable      bool   `json:"mig_capable"`
+	MigStrategy     string `json:"mig_strategy,omitempty"`
+	SharingStrategy string `json:"sharing_strategy,omitempty"`
+	Replicas        int64  `json:"replicas,omitempty"`
+	// Capacity and Allocatable of the nvidia.com/gpu resource
+	Capacity    int64 `json:"capacity"`
+	Allocatable int64 `json:"allocatable"`
+}
+
+// NfdDetected returns whether NFD found an NVIDIA PCI device on the node.
+func (n NodeGpus) NfdDetected() bool {
+	return len(n.PciDevices) > 0
+}
+
+// SharesGpus returns whether the nvidia.com/gpu devices of the node may not be
+// whole GPUs: time-slicing or MPS replicas, or MIG devices.
+func (n NodeGpus) SharesGpus() bool {
+	if n.Replicas > 1 || (len(n.SharingStrategy) > 0 && n.SharingStrategy != "none") {
+		return true
+	}
+	return n.MigCapable && len(n.MigStrategy) > 0 && n.MigStrategy != "none"
+}
+
+// Inventory is the GPU inventory of the nodes with an NVIDIA PCI device, GFD
+// labels or GPU capacity, sorted by node name.
+type Inventory struct {
+	Nodes []NodeGpus `json:"nodes"`
+}
+
+// Build returns the inventory of nodes from their NFD and GFD labels.
+func Build(nodes []corev1.Node) *Inventory {
+	inv := &Inventory{Nodes: []NodeGpus{}}
+	for _, node := range nodes {
+		n := NewNodeGpus(node)
+		if n.NfdDetected() || n.GfdLabeled || n.Capacity > 0 {
+			inv.Nodes = append(inv.Nodes, n)
+		}
+	}
+	sort.Slice(inv.Nodes, func(i, j int) bool {
+		return inv.Nodes[i].Node < inv.Nodes[j].Node
+	})
+	return inv
+}
+
+// NewNodeGpus returns the GPU inventory of node from its NFD and GFD labels.
+func NewNodeGpus(node corev1.Node) NodeGpus {
+	labels := node.Labels
+	n := NodeGpus{
+		Node:            node.Name,
+		PciDevices:      pciDevices(labels),
+		Product:         labels[LabelGpuProduct],
+		Family:          labels[LabelGpuFamily],
+		Machine:         labels[LabelGpuMachine],
+		Count:           intLabel(labels, LabelGpuCount),
+		MemoryMiB:       intLabel(labels, LabelGpuMemory),
+		Compute:         version(labels, LabelGpuComputeMajor, LabelGpuComputeMinor),
+		DriverVersion:   version(labels, LabelCudaDriverMajor, LabelCudaDriverMinor, LabelCudaDriverRev),
+		CudaVersion:     version(labels, LabelCudaRuntimeMajor, LabelCudaRuntimeMinor),
+		MigCapable:      labels[LabelMigCapable] == "true",
+		MigStrategy:     labels[LabelMigStrategy],
+		SharingStrategy: labels[LabelGpuSharingStrategy],
+		Replicas:        intLabel(labels, LabelGpuReplicas),
+	}
+	n.GfdLabeled = len(n.Product) > 0
+	if capacity, ok := node.Status.Capacity[GpuResource]; ok {
+		n.Capacity = capacity.Value()
+	}
+	if allocatable, ok := node.Status.Allocatable[GpuResource]; ok {
+		n.Allocatable = allocatable.Value()
+	}
+	return n
+}
+
+func pciDevices(labels map[string]string) []PciDevice {
+	devices := []PciDevice{}
+	for key, value := range labels {
+		if value != "true" || !strings.HasPrefix(key, nfdPciPrefix) || !strings.HasSuffix(key, nfdPciPresent) {
+			continue
+		}
+		if device, ok := ParsePciLabel(key); ok {
+			devices = append(devices, device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].String() < devices[j].String()
+	})
+	return devices
+}
+
+// ParsePciLabel parses the NFD label of an NVIDIA PCI device, e.g.
+// feature.node.kubernetes.io/pci-0302_10de.present. The fields before the
+// vendor are the class, the one after it the device.
+func ParsePciLabel(key string) (PciDevice, bool) {
+	fields := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, nfdPciPrefix), nfdPciPresent), "_")
+	for i, field := range fields {
+		if field != NvidiaVendorID {
+			continue
+		}
+		device := PciDevice{Vendor: field}
+		if i > 0 {
+			device.Class = fields[i-1]
+		}
+		if i+1 < len(fields) {
+			device.Device = fields[i+1]
+		}
+		return device, true
+	}
+	return PciDevice{}, false
+}
+
+// labelValue returns the value of the label, or of its newer GFD name.
+func labelValue(labels map[string]string, label string) string {
+	if value, ok := labels[label]; ok {
+		return value
+	}
+	return labels[gfdLabelAliases[label]]
+}
+
+func intLabel(labels map[string]string, label string) int64 {
+	value, err := strconv.ParseInt(labelValue(labels, label), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// version joins the labels with dots, empty when the first label is missing.
+func version(labels map[string]string, parts ...string) string {
+	values := []string{}
+	for _, part := range parts {
+		value := labelValue(labels, part)
+		if len(value) == 0 {
+			break
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ".")
+}
+
+// NfdNodes returns the nodes with an NVIDIA PCI device.
+func (inv *Inventory) NfdNodes() []NodeGpus {
+	nodes := []NodeGpus{}
+	for _, n := range inv.Nodes {
+		if n.NfdDetected() {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// GfdNodes returns the nodes labeled by GPU Feature Discovery.
+func (inv *Inventory) GfdNodes() []NodeGpus {
+	nodes := []NodeGpus{}
+	for _, n := range inv.Nodes {
+		if n.GfdLabeled {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// TotalCapacity returns the nvidia.com/gpu capacity of all the nodes.
+func (inv *Inventory) TotalCapacity() int64 {
+	var total int64
+	for _, n := range inv.Nodes {
+		total += n.Capacity
+	}
+	return total
+}
+
+// MinComputeCapability returns whether all the GFD labeled nodes have GPUs of
+// at least the compute capability major.minor, false without GFD labels.
+func (inv *Inventory) MinComputeCapability(major int, minor int) bool {
+	gfdNodes := inv.GfdNodes()
+	for _, n := range gfdNodes {
+		nodeMajor, nodeMinor, ok := parseCompute(n.Compute)
+		if !ok || nodeMajor < major || (nodeMajor == major && nodeMinor < minor) {
+			return false
+		}
+	}
+	return len(gfdNodes) > 0
+}
+
+func parseCompute(compute string) (int, int, bool) {
+	majorValue, minorValue, _ := strings.Cut(compute, ".")
+	major, err := strconv.Atoi(majorValue)
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, _ := strconv.Atoi(minorValue)
+	return major, minor, true
+}
+
+// WriteTable writes a table of the GPU nodes.
+func (inv *Inventory) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tPRODUCT\tCOUNT\tCAPACITY\tMEMORY\tDRIVER\tCUDA\tCOMPUTE\tMIG\tSHARING\tPCI")
+	for _, n := range inv.Nodes {
+		pci := []string{}
+		for _, d := range n.PciDevices {
+			pci = append(pci, d.String())
+		}
+		mig := "-"
+		if n.MigCapable {
+			mig = "capable"
+			if len(n.MigStrategy) > 0 {
+				mig = n.MigStrategy
+			}
+		}
+		memory := "-"
+		if n.MemoryMiB > 0 {
+			memory = fmt.Sprintf("%vMiB", n.MemoryMiB)
+		}
+		sharing := "-"
+		if len(n.SharingStrategy) > 0 && n.SharingStrategy != "none" {
+			sharing = fmt.Sprintf("%v x%v", n.SharingStrategy, n.Replicas)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			n.Node, orDash(n.Product), n.Count, n.Capacity, memory, orDash(n.DriverVersion),
+			orDash(n.CudaVersion), orDash(n.Compute), mig, sharing, orDash(strings.Join(pci, ",")))
+	}
+	return tw.Flush()
+}
+
+func orDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
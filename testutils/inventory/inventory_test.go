@@ -0,0 +1,143 @@
+package inventory
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func node(name string, labels map[string]string, gpus int64) corev1.Node {
+	n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	if gpus > 0 {
+		n.Status.Capacity = corev1.ResourceList{GpuResource: *resource.NewQuantity(gpus, resource.DecimalSI)}
+		n.Status.Allocatable = corev1.ResourceList{GpuResource: *resource.NewQuantity(gpus, resource.DecimalSI)}
+	}
+	return n
+}
+
+func TestParsePciLabel(t *testing.T) {
+	tests := []struct {
+		label    string
+		expected PciDevice
+		ok       bool
+	}{
+		{"feature.node.kubernetes.io/pci-10de.present", PciDevice{Vendor: "10de"}, true},
+		{"feature.node.kubernetes.io/pci-0302_10de.present", PciDevice{Class: "0302", Vendor: "10de"}, true},
+		{"feature.node.kubernetes.io/pci-0300_10de_1eb8.present", PciDevice{Class: "0300", Vendor: "10de", Device: "1eb8"}, true},
+		{"feature.node.kubernetes.io/pci-0300_1d0f.present", PciDevice{}, false},
+	}
+	for _, test := range tests {
+		device, ok := ParsePciLabel(test.label)
+		if ok != test.ok || device != test.expected {
+			t.Errorf("%v: expected %v %v, got %v %v", test.label, test.expected, test.ok, device, ok)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	nodes := []corev1.Node{
+		node("worker-b", map[string]string{
+			"feature.node.kubernetes.io/pci-0302_10de.present": "true",
+			"feature.node.kubernetes.io/pci-0300_1d0f.present": "true",
+			LabelGpuPresent:         "true",
+			LabelGpuProduct:         "NVIDIA-A100-SXM4-40GB",
+			LabelGpuCount:           "8",
+			LabelGpuMemory:          "40960",
+			LabelGpuFamily:          "ampere",
+			LabelGpuComputeMajor:    "8",
+			LabelGpuComputeMinor:    "0",
+			LabelCudaDriverMajor:    "535",
+			LabelCudaDriverMinor:    "104",
+			LabelCudaDriverRev:      "05",
+			LabelCudaRuntimeMajor:   "12",
+			LabelCudaRuntimeMinor:   "2",
+			LabelMigCapable:         "true",
+			LabelMigStrategy:        "single",
+			LabelGpuSharingStrategy: "none",
+		}, 8),
+		node("worker-a", map[string]string{
+			"feature.node.kubernetes.io/pci-10de.present": "true",
+			LabelGpuProduct:                         "Tesla-T4",
+			LabelGpuCount:                           "1",
+			"nvidia.com/cuda.driver-version.major":  "550",
+			"nvidia.com/cuda.driver-version.minor":  "54",
+			"nvidia.com/cuda.runtime-version.major": "12",
+			LabelGpuComputeMajor:                    "7",
+			LabelGpuComputeMinor:                    "5",
+			LabelMigCapable:                         "false",
+			LabelGpuSharingStrategy:                 "time-slicing",
+			LabelGpuReplicas:                        "4",
+		}, 4),
+		node("worker-c", map[string]string{
+			"feature.node.kubernetes.io/pci-0300_1d0f.present": "true",
+		}, 0),
+		// NFD found the GPU, GFD did not label the node yet
+		node("worker-d", map[string]string{
+			"feature.node.kubernetes.io/pci-0302_10de.present": "true",
+		}, 0),
+	}
+	inv := Build(nodes)
+
+	names := []string{}
+	for _, n := range inv.Nodes {
+		names = append(names, n.Node)
+	}
+	if !reflect.DeepEqual(names, []string{"worker-a", "worker-b", "worker-d"}) {
+		t.Fatalf("unexpected GPU nodes %v", names)
+	}
+	expected := NodeGpus{
+		Node:            "worker-b",
+		PciDevices:      []PciDevice{{Class: "0302", Vendor: "10de"}},
+		GfdLabeled:      true,
+		Product:         "NVIDIA-A100-SXM4-40GB",
+		Family:          "ampere",
+		Count:           8,
+		MemoryMiB:       40960,
+		Compute:         "8.0",
+		DriverVersion:   "535.104.05",
+		CudaVersion:     "12.2",
+		MigCapable:      true,
+		MigStrategy:     "single",
+		SharingStrategy: "none",
+		Capacity:        8,
+		Allocatable:     8,
+	}
+	if !reflect.DeepEqual(inv.Nodes[1], expected) {
+		t.Errorf("expected %+v, got %+v", expected, inv.Nodes[1])
+	}
+	if inv.Nodes[0].DriverVersion != "550.54" || inv.Nodes[0].CudaVersion != "12" {
+		t.Errorf("newer GFD labels not read: driver %v cuda %v", inv.Nodes[0].DriverVersion, inv.Nodes[0].CudaVersion)
+	}
+
+	if len(inv.NfdNodes()) != 3 || len(inv.GfdNodes()) != 2 {
+		t.Errorf("unexpected node counts: nfd %v gfd %v", len(inv.NfdNodes()), len(inv.GfdNodes()))
+	}
+	if !inv.Nodes[0].SharesGpus() || !inv.Nodes[1].SharesGpus() || inv.Nodes[2].SharesGpus() {
+		t.Errorf("unexpected GPU sharing: time-slicing %v, MIG %v, none %v", inv.Nodes[0].SharesGpus(), inv.Nodes[1].SharesGpus(), inv.Nodes[2].SharesGpus())
+	}
+	if inv.TotalCapacity() != 12 {
+		t.Errorf("expected a total capacity of 12, got %v", inv.TotalCapacity())
+	}
+	if !inv.MinComputeCapability(7, 5) || inv.MinComputeCapability(8, 0) {
+		t.Errorf("unexpected minimum compute capability")
+	}
+
+	table := new(bytes.Buffer)
+	if err := inv.WriteTable(table); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 nodes, got:\n%v", table.String())
+	}
+	for _, value := range []string{"Tesla-T4", "time-slicing x4", "0302_10de", "single", "535.104.05"} {
+		if !strings.Contains(table.String(), value) {
+			t.Errorf("table has no %q:\n%v", value, table.String())
+		}
+	}
+}
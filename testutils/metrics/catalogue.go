@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+)
+
+// Components exposing metrics, as scraped by test_gpu_operator_metrics.
+const (
+	GpuOperator        = "gpu-operator"
+	NodeStatusExporter = "node-status-exporter"
+	DcgmExporter       = "dcgm-exporter"
+)
+
+// ExpectedMetric is a metric a component exposes since a GPU operator
+// version, and until the version it was removed in, if any. An Optional
+// metric is reported when missing but does not fail the tests, e.g. a DCGM
+// field not in the default DCGM exporter counters of every release.
+type ExpectedMetric struct {
+	Component string
+	Name      string
+	Since     semver.Version
+	RemovedIn *semver.Version
+	Optional  bool
+}
+
+var v1_9 = semver.MustParse("1.9.0")
+
+// catalogue lists the metrics whose renaming or removal between two operator
+// releases should be noticed. When a metric is renamed, set RemovedIn on the
+// old entry and add the new name with Since.
+var catalogue = []ExpectedMetric{
+	{Component: GpuOperator, Name: "gpu_operator_gpu_nodes_total", Since: v1_9},
+	{Component: GpuOperator, Name: "gpu_operator_reconciliation_status", Since: v1_9},
+	{Component: GpuOperator, Name: "gpu_operator_reconciliation_total", Since: v1_9},
+	{Component: GpuOperator, Name: "gpu_operator_reconciliation_failed_total", Since: v1_9},
+	{Component: GpuOperator, Name: "gpu_operator_reconciliation_last_success_ts_seconds", Since: v1_9},
+	{Component: NodeStatusExporter, Name: "gpu_operator_node_driver_ready", Since: v1_9},
+	{Component: NodeStatusExporter, Name: "gpu_operator_node_toolkit_ready", Since: v1_9},
+	{Component: NodeStatusExporter, Name: "gpu_operator_node_device_plugin_ready", Since: v1_9},
+	{Component: NodeStatusExporter, Name: "gpu_operator_node_device_plugin_devices_total", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_GPU_UTIL", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_FB_USED", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_FB_FREE", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_GPU_TEMP", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_POWER_USAGE", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_SM_CLOCK", Since: v1_9},
+	{Component: DcgmExporter, Name: "DCGM_FI_DEV_MEM_CLOCK", Since: v1_9, Optional: true},
+}
+
+// ExpectedMetrics returns the names of the required metrics component
+// exposes with the given GPU operator version.
+func ExpectedMetrics(component string, operatorVersion semver.Version) []string {
+	return catalogueMetrics(component, operatorVersion, false)
+}
+
+// OptionalMetrics returns the names of the optional metrics component may
+// expose with the given GPU operator version.
+func OptionalMetrics(component string, operatorVersion semver.Version) []string {
+	return catalogueMetrics(component, operatorVersion, true)
+}
+
+func catalogueMetrics(component string, operatorVersion semver.Version, optional bool) []string {
+	// Pre-releases and builds of a version expose the metrics of the release
+	v := semver.Version{Major: operatorVersion.Major, Minor: operatorVersion.Minor, Patch: operatorVersion.Patch}
+	names := []string{}
+	for _, metric := range catalogue {
+		if metric.Component != component || metric.Optional != optional || v.LT(metric.Since) {
+			continue
+		}
+		if metric.RemovedIn != nil && v.GTE(*metric.RemovedIn) {
+			continue
+		}
+		names = append(names, metric.Name)
+	}
+	return names
+}
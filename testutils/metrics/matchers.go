@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// HaveMetric succeeds when the scrape has at least one sample named name.
+// The actual value is Families, or the scrape as a string or []byte.
+func HaveMetric(name string) types.GomegaMatcher {
+	return &metricMatcher{name: name}
+}
+
+// HaveMetricWithLabels succeeds when the scrape has a sample named name whose
+// labels include labels.
+func HaveMetricWithLabels(name string, labels map[string]string) types.GomegaMatcher {
+	return &metricMatcher{name: name, labels: labels}
+}
+
+// MetricValueInRange succeeds when the scrape has samples named name with
+// labels, and all of them have a value between min and max, both included.
+func MetricValueInRange(name string, labels map[string]string, min float64, max float64) types.GomegaMatcher {
+	return &metricMatcher{name: name, labels: labels, checkRange: true, min: min, max: max}
+}
+
+type metricMatcher struct {
+	name       string
+	labels     map[string]string
+	checkRange bool
+	min        float64
+	max        float64
+	// samples and outOfRange are kept for the failure messages
+	samples    []Sample
+	outOfRange []Sample
+}
+
+func (m *metricMatcher) Match(actual interface{}) (bool, error) {
+	families, err := toFamilies(actual)
+	if err != nil {
+		return false, err
+	}
+	m.samples = families.Samples(m.name, m.labels)
+	m.outOfRange = []Sample{}
+	if len(m.samples) == 0 {
+		return false, nil
+	}
+	if !m.checkRange {
+		return true, nil
+	}
+	for _, sample := range m.samples {
+		if !(sample.Value >= m.min && sample.Value <= m.max) {
+			m.outOfRange = append(m.outOfRange, sample)
+		}
+	}
+	return len(m.outOfRange) == 0, nil
+}
+
+func (m *metricMatcher) FailureMessage(actual interface{}) string {
+	if len(m.samples) == 0 {
+		return fmt.Sprintf("Expected scrape to have metric %v\n%v", m.describe(), scrapeSummary(actual))
+	}
+	return fmt.Sprintf("Expected %v to be in [%v, %v], out of range samples:\n%v", m.describe(), m.min, m.max, format.Object(m.outOfRange, 1))
+}
+
+func (m *metricMatcher) NegatedFailureMessage(actual interface{}) string {
+	if m.checkRange {
+		return fmt.Sprintf("Expected %v not to be in [%v, %v], samples:\n%v", m.describe(), m.min, m.max, format.Object(m.samples, 1))
+	}
+	return fmt.Sprintf("Expected scrape not to have metric %v, samples:\n%v", m.describe(), format.Object(m.samples, 1))
+}
+
+func (m *metricMatcher) describe() string {
+	if len(m.labels) == 0 {
+		return m.name
+	}
+	return fmt.Sprintf("%v%v", m.name, m.labels)
+}
+
+func toFamilies(actual interface{}) (Families, error) {
+	switch a := actual.(type) {
+	case Families:
+		return a, nil
+	case string:
+		return Parse(a)
+	case []byte:
+		return Parse(string(a))
+	}
+	return nil, fmt.Errorf("metric matchers expect Families, string or []byte, got:\n%v", format.Object(actual, 1))
+}
+
+func scrapeSummary(actual interface{}) string {
+	families, err := toFamilies(actual)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("metrics in scrape: %v", strings.Join(families.Names(), ", "))
+}
@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sample is a single line of the Prometheus text exposition format.
+type Sample struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+	// Timestamp is in milliseconds since epoch, 0 when not exposed.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// Family groups the samples of a metric. The _bucket, _sum and _count samples
+// of histograms and summaries belong to the family of the base metric.
+type Family struct {
+	Name    string   `json:"name"`
+	Help    string   `json:"help,omitempty"`
+	Type    string   `json:"type"`
+	Samples []Sample `json:"samples"`
+}
+
+// Families are the metric families of a scrape, by name.
+type Families map[string]*Family
+
+// Parse parses a scrape in the Prometheus text exposition format.
+func Parse(text string) (Families, error) {
+	families := Families{}
+	getFamily := func(name string) *Family {
+		if family, ok := families[name]; ok {
+			return family
+		}
+		family := &Family{Name: name, Type: "untyped"}
+		families[name] = family
+		return family
+	}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 3 {
+				continue
+			}
+			switch fields[1] {
+			case "HELP":
+				if len(fields) == 4 {
+					getFamily(fields[2]).Help = unescape(fields[3], false)
+				}
+			case "TYPE":
+				if len(fields) != 4 {
+					return nil, fmt.Errorf("line %d: TYPE without a type: %q", i+1, line)
+				}
+				getFamily(fields[2]).Type = fields[3]
+			}
+			continue
+		}
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		family := getFamily(familyName(families, sample.Name))
+		family.Samples = append(family.Samples, sample)
+	}
+	return families, nil
+}
+
+// familyName returns the name of the histogram or summary family the sample
+// belongs to, or the sample name itself.
+func familyName(families Families, name string) string {
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		base := strings.TrimSuffix(name, suffix)
+		if family, ok := families[base]; ok && (family.Type == "histogram" || family.Type == "summary") {
+			return base
+		}
+	}
+	return name
+}
+
+func parseSample(line string) (Sample, error) {
+	sample := Sample{Labels: map[string]string{}}
+	end := strings.IndexAny(line, "{ \t")
+	if end < 0 {
+		return sample, fmt.Errorf("sample without a value: %q", line)
+	}
+	sample.Name = line[:end]
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseLabels(rest[1:])
+		if err != nil {
+			return sample, fmt.Errorf("%v: %q", err, line)
+		}
+		sample.Labels = labels
+		rest = rest[1+n:]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("expected a value and an optional timestamp: %q", line)
+	}
+	value, err := parseValue(fields[0])
+	if err != nil {
+		return sample, fmt.Errorf("invalid value %q: %v", fields[0], err)
+	}
+	sample.Value = value
+	if len(fields) == 2 {
+		sample.Timestamp, err = strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return sample, fmt.Errorf("invalid timestamp %q: %v", fields[1], err)
+		}
+	}
+	return sample, nil
+}
+
+// parseLabels parses the labels after the opening brace and returns the
+// number of bytes read, including the closing brace.
+func parseLabels(s string) (map[string]string, int, error) {
+	labels := map[string]string{}
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, 0, fmt.Errorf("label without a value")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label %v value is not quoted", name)
+		}
+		i++
+		start := i
+		for i < len(s) && s[i] != '"' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated value of label %v", name)
+		}
+		labels[name] = unescape(s[start:i], true)
+		i++
+	}
+}
+
+func unescape(s string, quotes bool) string {
+	replacements := []string{`\\`, `\`, `\n`, "\n"}
+	if quotes {
+		replacements = append(replacements, `\"`, `"`)
+	}
+	return strings.NewReplacer(replacements...).Replace(s)
+}
+
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Samples returns the samples named name, the family name or the full name
+// of a histogram or summary sample, whose labels include labels.
+func (f Families) Samples(name string, labels map[string]string) []Sample {
+	samples := []Sample{}
+	family, ok := f[familyName(f, name)]
+	if !ok {
+		return samples
+	}
+	for _, sample := range family.Samples {
+		if sample.Name != name {
+			continue
+		}
+		if hasLabels(sample, labels) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Names returns the sorted names of the families having samples.
+func (f Families) Names() []string {
+	names := []string{}
+	for name, family := range f {
+		if len(family.Samples) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasLabels(sample Sample, labels map[string]string) bool {
+	for k, v := range labels {
+		if val, ok := sample.Labels[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+const scrape = `# HELP gpu_operator_reconciliation_status 1 if the reconciliation is successful
+# TYPE gpu_operator_reconciliation_status gauge
+gpu_operator_reconciliation_status 1
+# TYPE DCGM_FI_DEV_GPU_UTIL gauge
+DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="GPU-a",Hostname="node-1",modelName="Tesla T4"} 98
+DCGM_FI_DEV_GPU_UTIL{gpu="1",UUID="GPU-b",Hostname="node-1",modelName="Tesla \"T4\""} 3 1700000000000
+# TYPE reconcile_time_seconds histogram
+reconcile_time_seconds_bucket{le="0.5"} 4
+reconcile_time_seconds_bucket{le="+Inf"} 5
+reconcile_time_seconds_sum 2.5
+reconcile_time_seconds_count 5
+untyped_metric NaN
+`
+
+func TestParse(t *testing.T) {
+	families, err := Parse(scrape)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	status, ok := families["gpu_operator_reconciliation_status"]
+	if !ok || status.Type != "gauge" || status.Help != "1 if the reconciliation is successful" {
+		t.Errorf("unexpected reconciliation status family: %+v", status)
+	}
+	utilisation := families.Samples("DCGM_FI_DEV_GPU_UTIL", map[string]string{"gpu": "1"})
+	if len(utilisation) != 1 {
+		t.Fatalf("expected a single sample for GPU 1, got %v", utilisation)
+	}
+	if utilisation[0].Value != 3 || utilisation[0].Timestamp != 1700000000000 || utilisation[0].Labels["modelName"] != `Tesla "T4"` {
+		t.Errorf("unexpected sample for GPU 1: %+v", utilisation[0])
+	}
+	histogram := families["reconcile_time_seconds"]
+	if histogram == nil || len(histogram.Samples) != 4 {
+		t.Fatalf("histogram samples should be grouped in a single family: %+v", families.Names())
+	}
+	buckets := families.Samples("reconcile_time_seconds_bucket", map[string]string{"le": "+Inf"})
+	if len(buckets) != 1 || buckets[0].Value != 5 {
+		t.Errorf("unexpected +Inf bucket: %v", buckets)
+	}
+	untyped := families.Samples("untyped_metric", nil)
+	if len(untyped) != 1 || !math.IsNaN(untyped[0].Value) || families["untyped_metric"].Type != "untyped" {
+		t.Errorf("unexpected untyped sample: %v", untyped)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, text := range []string{
+		"metric_without_value",
+		`metric{label="value} 1`,
+		`metric{label=value} 1`,
+		"metric one",
+		"metric 1 2 3",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q) should fail", text)
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    bool
+		matchers func() (bool, error)
+	}{
+		{"HaveMetric", true, func() (bool, error) { return HaveMetric("gpu_operator_reconciliation_status").Match(scrape) }},
+		{"HaveMetric missing", false, func() (bool, error) { return HaveMetric("gpu_operator_gpu_nodes_total").Match(scrape) }},
+		{"HaveMetricWithLabels", true, func() (bool, error) {
+			return HaveMetricWithLabels("DCGM_FI_DEV_GPU_UTIL", map[string]string{"Hostname": "node-1", "gpu": "0"}).Match([]byte(scrape))
+		}},
+		{"HaveMetricWithLabels wrong label", false, func() (bool, error) {
+			return HaveMetricWithLabels("DCGM_FI_DEV_GPU_UTIL", map[string]string{"Hostname": "node-2"}).Match(scrape)
+		}},
+		{"MetricValueInRange", true, func() (bool, error) {
+			return MetricValueInRange("DCGM_FI_DEV_GPU_UTIL", map[string]string{"gpu": "0"}, 50, 100).Match(scrape)
+		}},
+		{"MetricValueInRange out of range", false, func() (bool, error) {
+			return MetricValueInRange("DCGM_FI_DEV_GPU_UTIL", nil, 50, 100).Match(scrape)
+		}},
+	}
+	for _, test := range tests {
+		match, err := test.matchers()
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+		}
+		if match != test.match {
+			t.Errorf("%v: expected match to be %v", test.name, test.match)
+		}
+	}
+	if _, err := HaveMetric("any").Match(42); err == nil {
+		t.Errorf("HaveMetric should fail on an int")
+	}
+}
+
+func TestExpectedMetrics(t *testing.T) {
+	if names := ExpectedMetrics(GpuOperator, semver.MustParse("1.8.2")); len(names) != 0 {
+		t.Errorf("no metrics expected before 1.9, got %v", names)
+	}
+	names := ExpectedMetrics(GpuOperator, semver.MustParse("23.9.0-rc.1"))
+	found := false
+	for _, name := range names {
+		found = found || name == "gpu_operator_reconciliation_status"
+	}
+	if !found {
+		t.Errorf("gpu_operator_reconciliation_status should be expected in 23.9.0, got %v", names)
+	}
+
+	for _, name := range ExpectedMetrics(DcgmExporter, semver.MustParse("23.9.0")) {
+		if name == "DCGM_FI_DEV_MEM_CLOCK" {
+			t.Errorf("optional DCGM_FI_DEV_MEM_CLOCK should not be required")
+		}
+	}
+	if names := OptionalMetrics(DcgmExporter, semver.MustParse("23.9.0")); len(names) != 1 || names[0] != "DCGM_FI_DEV_MEM_CLOCK" {
+		t.Errorf("expected DCGM_FI_DEV_MEM_CLOCK to be optional, got %v", names)
+	}
+}
@@ -0,0 +1,449 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+// HistoryRecord is a run as stored in the history, one JSON document per line.
+type HistoryRecord struct {
+	RunID           string        `json:"run_id"`
+	Time            time.Time     `json:"time"`
+	OcpVersion      string        `json:"ocp_version"`
+	OperatorVersion string        `json:"operator_version"`
+	Channel         string        `json:"channel,omitempty"`
+	Passed          bool          `json:"passed"`
+	Stages          []StageRecord `json:"stages"`
+	Specs           []SpecRecord  `json:"specs"`
+}
+
+type StageRecord struct {
+	Name       string                   `json:"name"`
+	Passed     bool                     `json:"passed"`
+	Duration   time.Duration            `json:"duration"`
+	Milestones map[string]time.Duration `json:"milestones,omitempty"`
+}
+
+// SpecRecord is the state of a spec, prefixed by its stage.
+type SpecRecord struct {
+	Stage string          `json:"stage"`
+	Spec  string          `json:"spec"`
+	State types.SpecState `json:"state"`
+}
+
+// NewHistoryRecord returns the record of a run summary. runID defaults to the
+// start time of the first stage, the versions and a hash of the stage
+// directories of the artifact dir, the same for every ingest of the run.
+func NewHistoryRecord(summary *RunSummary, runID string) HistoryRecord {
+	record := HistoryRecord{
+		RunID:           runID,
+		Time:            summary.GeneratedAt,
+		OcpVersion:      summary.OcpVersion,
+		OperatorVersion: summary.OperatorVersion,
+		Channel:         summary.Channel,
+		Passed:          summary.Passed,
+		Stages:          []StageRecord{},
+		Specs:           []SpecRecord{},
+	}
+	if len(summary.Stages) > 0 {
+		record.Time = summary.Stages[0].StartTime
+	}
+	if len(record.RunID) == 0 {
+		record.RunID = defaultRunID(summary)
+	}
+	for _, stage := range summary.Stages {
+		stageRecord := StageRecord{Name: stage.Name, Passed: stage.Passed, Duration: stage.Duration}
+		for _, milestone := range stage.Milestones {
+			if stageRecord.Milestones == nil {
+				stageRecord.Milestones = map[string]time.Duration{}
+			}
+			stageRecord.Milestones[milestone.Name] = milestone.Duration
+		}
+		record.Stages = append(record.Stages, stageRecord)
+		for _, result := range stage.Results {
+			record.Specs = append(record.Specs, SpecRecord{Stage: stage.Name, Spec: result.Spec, State: result.State})
+		}
+	}
+	return record
+}
+
+// defaultRunID identifies a run by its summary, without the generation time
+// of the summary which changes when it is regenerated.
+func defaultRunID(summary *RunSummary) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\n%v\n%v\n", summary.OcpVersion, summary.OperatorVersion, summary.Channel)
+	for _, stage := range summary.Stages {
+		// The stage directories are named after the start time of the stage
+		fmt.Fprintf(h, "%v %v\n", stage.Dir, stage.StartTime.UTC().Format(time.RFC3339Nano))
+	}
+	id := hex.EncodeToString(h.Sum(nil))[:12]
+	if len(summary.Stages) == 0 {
+		return fmt.Sprintf("%v_%v_%v", summary.OcpVersion, summary.OperatorVersion, id)
+	}
+	start := summary.Stages[0].StartTime.UTC().Format("20060102T150405")
+	return fmt.Sprintf("%v_%v_%v_%v", start, summary.OcpVersion, summary.OperatorVersion, id)
+}
+
+// sameRun returns whether two records are the same run, ingested with
+// different run IDs.
+func sameRun(a HistoryRecord, b HistoryRecord) bool {
+	if !a.Time.Equal(b.Time) || a.OcpVersion != b.OcpVersion || a.OperatorVersion != b.OperatorVersion ||
+		a.Channel != b.Channel || len(a.Stages) != len(b.Stages) || len(a.Specs) != len(b.Specs) {
+		return false
+	}
+	for i := range a.Stages {
+		if a.Stages[i].Name != b.Stages[i].Name || a.Stages[i].Duration != b.Stages[i].Duration {
+			return false
+		}
+	}
+	for i := range a.Specs {
+		if a.Specs[i] != b.Specs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadHistory reads the records of a JSON-lines history file. A missing file
+// is an empty history.
+func ReadHistory(filename string) ([]HistoryRecord, error) {
+	records := []HistoryRecord{}
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// Records of runs with many specs exceed the default 64k line limit
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		record := HistoryRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", filename, line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// AppendHistory appends the record to the history file, unless the run is
+// already stored, with the same ID or not. It returns whether the record was
+// added.
+func AppendHistory(filename string, record HistoryRecord) (bool, error) {
+	records, err := ReadHistory(filename)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range records {
+		if r.RunID == record.RunID || sameRun(r, record) {
+			return false, nil
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err == nil, err
+}
+
+// HistoryFilter selects runs. Versions match on a prefix of dot-separated
+// components, i.e. "4.14" matches "4.14.3" but not "4.1".
+type HistoryFilter struct {
+	OcpVersion      string
+	OperatorVersion string
+	Channel         string
+}
+
+func (f HistoryFilter) String() string {
+	parts := []string{}
+	if len(f.OcpVersion) > 0 {
+		parts = append(parts, "OCP "+f.OcpVersion)
+	}
+	if len(f.OperatorVersion) > 0 {
+		parts = append(parts, "operator "+f.OperatorVersion)
+	}
+	if len(f.Channel) > 0 {
+		parts = append(parts, "channel "+f.Channel)
+	}
+	if len(parts) == 0 {
+		return "all runs"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func matchVersion(version string, prefix string) bool {
+	return len(prefix) == 0 || version == prefix || strings.HasPrefix(version, prefix+".")
+}
+
+func (f HistoryFilter) Match(record HistoryRecord) bool {
+	return matchVersion(record.OcpVersion, f.OcpVersion) &&
+		matchVersion(record.OperatorVersion, f.OperatorVersion) &&
+		(len(f.Channel) == 0 || record.Channel == f.Channel)
+}
+
+// FilterHistory returns the records matching the filter, oldest first.
+func FilterHistory(records []HistoryRecord, filter HistoryFilter) []HistoryRecord {
+	matching := []HistoryRecord{}
+	for _, record := range records {
+		if filter.Match(record) {
+			matching = append(matching, record)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].Time.Before(matching[j].Time)
+	})
+	return matching
+}
+
+// SpecStats counts the runs a spec passed and failed in.
+type SpecStats struct {
+	Passed int
+	Failed int
+}
+
+func (s SpecStats) String() string {
+	return fmt.Sprintf("%v/%v failed", s.Failed, s.Passed+s.Failed)
+}
+
+// SpecChange is a spec whose result differs between two sets of runs.
+type SpecChange struct {
+	Stage string
+	Spec  string
+	From  SpecStats
+	To    SpecStats
+}
+
+// StageDurations are the durations of a stage over a set of runs.
+type StageDurations struct {
+	Runs int
+	Mean time.Duration
+	Min  time.Duration
+	Max  time.Duration
+}
+
+type StageChange struct {
+	Stage string
+	From  StageDurations
+	To    StageDurations
+}
+
+// Comparison compares the runs matching two filters.
+type Comparison struct {
+	From         HistoryFilter
+	To           HistoryFilter
+	FromRuns     int
+	ToRuns       int
+	NewFailures  []SpecChange
+	Fixed        []SpecChange
+	StillFailing []SpecChange
+	Stages       []StageChange
+}
+
+func specStats(records []HistoryRecord) map[[2]string]*SpecStats {
+	stats := map[[2]string]*SpecStats{}
+	for _, record := range records {
+		for _, spec := range record.Specs {
+			key := [2]string{spec.Stage, spec.Spec}
+			if stats[key] == nil {
+				stats[key] = &SpecStats{}
+			}
+			switch {
+			case spec.State.Is(types.SpecStatePassed):
+				stats[key].Passed++
+			case spec.State.Is(types.SpecStateFailureStates):
+				stats[key].Failed++
+			}
+		}
+	}
+	return stats
+}
+
+func stageDurations(records []HistoryRecord) map[string]*StageDurations {
+	durations := map[string]*StageDurations{}
+	for _, record := range records {
+		for _, stage := range record.Stages {
+			d := durations[stage.Name]
+			if d == nil {
+				d = &StageDurations{Min: stage.Duration, Max: stage.Duration}
+				durations[stage.Name] = d
+			}
+			if stage.Duration < d.Min {
+				d.Min = stage.Duration
+			}
+			if stage.Duration > d.Max {
+				d.Max = stage.Duration
+			}
+			// Mean holds the sum until all the runs are counted
+			d.Mean += stage.Duration
+			d.Runs++
+		}
+	}
+	for _, d := range durations {
+		d.Mean /= time.Duration(d.Runs)
+	}
+	return durations
+}
+
+// Compare returns the specs that started failing, were fixed or still fail,
+// from the runs matching from to the runs matching to, and the change of the
+// stage durations. A spec is failing when it failed in any of the runs, specs
+// not run on both sides are ignored.
+func Compare(records []HistoryRecord, from HistoryFilter, to HistoryFilter) *Comparison {
+	fromRecords, toRecords := FilterHistory(records, from), FilterHistory(records, to)
+	comparison := &Comparison{From: from, To: to, FromRuns: len(fromRecords), ToRuns: len(toRecords)}
+	fromStats, toStats := specStats(fromRecords), specStats(toRecords)
+	for key, toSpec := range toStats {
+		fromSpec, ok := fromStats[key]
+		if !ok || fromSpec.Passed+fromSpec.Failed == 0 || toSpec.Passed+toSpec.Failed == 0 {
+			continue
+		}
+		change := SpecChange{Stage: key[0], Spec: key[1], From: *fromSpec, To: *toSpec}
+		switch {
+		case fromSpec.Failed == 0 && toSpec.Failed > 0:
+			comparison.NewFailures = append(comparison.NewFailures, change)
+		case fromSpec.Failed > 0 && toSpec.Failed == 0:
+			comparison.Fixed = append(comparison.Fixed, change)
+		case fromSpec.Failed > 0 && toSpec.Failed > 0:
+			comparison.StillFailing = append(comparison.StillFailing, change)
+		}
+	}
+	for _, changes := range [][]SpecChange{comparison.NewFailures, comparison.Fixed, comparison.StillFailing} {
+		sort.Slice(changes, func(i, j int) bool {
+			if changes[i].Stage == changes[j].Stage {
+				return changes[i].Spec < changes[j].Spec
+			}
+			return changes[i].Stage < changes[j].Stage
+		})
+	}
+	fromDurations, toDurations := stageDurations(fromRecords), stageDurations(toRecords)
+	for stage, toDuration := range toDurations {
+		change := StageChange{Stage: stage, To: *toDuration}
+		if fromDuration, ok := fromDurations[stage]; ok {
+			change.From = *fromDuration
+		}
+		comparison.Stages = append(comparison.Stages, change)
+	}
+	for stage, fromDuration := range fromDurations {
+		if _, ok := toDurations[stage]; !ok {
+			comparison.Stages = append(comparison.Stages, StageChange{Stage: stage, From: *fromDuration})
+		}
+	}
+	sort.Slice(comparison.Stages, func(i, j int) bool {
+		return comparison.Stages[i].Stage < comparison.Stages[j].Stage
+	})
+	return comparison
+}
+
+func formatDuration(d StageDurations) string {
+	if d.Runs == 0 {
+		return "-"
+	}
+	return d.Mean.Round(time.Second).String()
+}
+
+func formatDurationChange(change StageChange) string {
+	if change.From.Runs == 0 || change.To.Runs == 0 {
+		return "-"
+	}
+	delta := (change.To.Mean - change.From.Mean).Round(time.Second)
+	sign := "+"
+	if delta < 0 {
+		sign = ""
+	}
+	percent := float64(change.To.Mean-change.From.Mean) / float64(change.From.Mean) * 100
+	return fmt.Sprintf("%v%v (%v%.0f%%)", sign, delta, sign, percent)
+}
+
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(text)
+}
+
+func writeSpecChanges(w io.Writer, title string, changes []SpecChange) {
+	fmt.Fprintf(w, "\n### %v (%v)\n\n", title, len(changes))
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "None")
+		return
+	}
+	fmt.Fprintln(w, "| Stage | Spec | Before | After |")
+	fmt.Fprintln(w, "|---|---|---|---|")
+	for _, change := range changes {
+		fmt.Fprintf(w, "| %v | %v | %v | %v |\n", change.Stage, escapeMarkdown(change.Spec), change.From, change.To)
+	}
+}
+
+// WriteMarkdown writes the comparison as markdown, to be pasted into a PR
+// comment.
+func (c *Comparison) WriteMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "## %v (%v runs) → %v (%v runs)\n", c.From, c.FromRuns, c.To, c.ToRuns)
+	writeSpecChanges(w, "New failures", c.NewFailures)
+	writeSpecChanges(w, "Fixed", c.Fixed)
+	writeSpecChanges(w, "Still failing", c.StillFailing)
+	fmt.Fprintf(w, "\n### Stage durations\n\n")
+	fmt.Fprintln(w, "| Stage | Before | After | Change |")
+	fmt.Fprintln(w, "|---|---|---|---|")
+	for _, stage := range c.Stages {
+		fmt.Fprintf(w, "| %v | %v | %v | %v |\n", stage.Stage, formatDuration(stage.From), formatDuration(stage.To), formatDurationChange(stage))
+	}
+}
+
+// WriteStageTrend writes the duration of a stage in each of the records, as
+// markdown.
+func WriteStageTrend(w io.Writer, records []HistoryRecord, stage string) {
+	fmt.Fprintf(w, "## %v duration\n\n", stage)
+	fmt.Fprintln(w, "| Run | OCP | Operator | Channel | Result | Duration |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|")
+	for _, record := range records {
+		for _, s := range record.Stages {
+			if s.Name != stage {
+				continue
+			}
+			result := "passed"
+			if !s.Passed {
+				result = "failed"
+			}
+			fmt.Fprintf(w, "| %v | %v | %v | %v | %v | %v |\n", record.RunID, record.OcpVersion, record.OperatorVersion,
+				record.Channel, result, s.Duration.Round(time.Second))
+		}
+	}
+}
+
+// WriteMilestoneTrend writes the duration of an install milestone in each of
+// the records, as markdown.
+func WriteMilestoneTrend(w io.Writer, records []HistoryRecord, milestone string) {
+	fmt.Fprintf(w, "## %v duration\n\n", milestone)
+	fmt.Fprintln(w, "| Run | OCP | Operator | Channel | Duration |")
+	fmt.Fprintln(w, "|---|---|---|---|---|")
+	for _, record := range records {
+		for _, s := range record.Stages {
+			if d, ok := s.Milestones[milestone]; ok {
+				fmt.Fprintf(w, "| %v | %v | %v | %v | %v |\n", record.RunID, record.OcpVersion, record.OperatorVersion,
+					record.Channel, d.Round(time.Second))
+			}
+		}
+	}
+}
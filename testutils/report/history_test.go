@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+func historySummary(start time.Time, ocp string, operator string, duration time.Duration, states map[string]types.SpecState) *RunSummary {
+	stage := Stage{Name: "wait_for_gpu_operator", StartTime: start, Duration: duration, Passed: true}
+	for spec, state := range states {
+		stage.Results = append(stage.Results, SpecResult{Spec: spec, State: state})
+		stage.Passed = stage.Passed && !state.Is(types.SpecStateFailureStates)
+	}
+	return &RunSummary{OcpVersion: ocp, OperatorVersion: operator, Channel: "stable", Stages: []Stage{stage}}
+}
+
+func TestHistory(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	summaries := []*RunSummary{
+		historySummary(start, "4.14.1", "23.6.1", 10*time.Minute, map[string]types.SpecState{
+			"driver ready": types.SpecStatePassed, "dcgm ready": types.SpecStateFailed, "toolkit ready": types.SpecStateFailed,
+		}),
+		historySummary(start.Add(time.Hour), "4.14.2", "23.9.0", 15*time.Minute, map[string]types.SpecState{
+			"driver ready": types.SpecStateFailed, "dcgm ready": types.SpecStatePassed, "toolkit ready": types.SpecStateTimedout,
+		}),
+		// Not matching the 4.14 filter
+		historySummary(start, "4.1.0", "23.9.0", time.Minute, map[string]types.SpecState{"dcgm ready": types.SpecStateFailed}),
+	}
+	for _, summary := range summaries {
+		added, err := AppendHistory(db, NewHistoryRecord(summary, ""))
+		if err != nil || !added {
+			t.Fatalf("AppendHistory failed: %v %v", added, err)
+		}
+	}
+	if added, err := AppendHistory(db, NewHistoryRecord(summaries[0], "")); err != nil || added {
+		t.Errorf("expected an ingested run to be skipped, got %v %v", added, err)
+	}
+	regenerated := *summaries[0]
+	regenerated.GeneratedAt = time.Now()
+	if added, err := AppendHistory(db, NewHistoryRecord(&regenerated, "")); err != nil || added {
+		t.Errorf("expected a run with a regenerated summary to be skipped, got %v %v", added, err)
+	}
+	if added, err := AppendHistory(db, NewHistoryRecord(summaries[0], "nightly-1")); err != nil || added {
+		t.Errorf("expected an ingested run with another ID to be skipped, got %v %v", added, err)
+	}
+	records, err := ReadHistory(db)
+	if err != nil {
+		t.Fatalf("ReadHistory failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %v", len(records))
+	}
+
+	comparison := Compare(records, HistoryFilter{OcpVersion: "4.14", OperatorVersion: "23.6"}, HistoryFilter{OcpVersion: "4.14", OperatorVersion: "23.9"})
+	if comparison.FromRuns != 1 || comparison.ToRuns != 1 {
+		t.Errorf("expected a run on each side, got %v and %v", comparison.FromRuns, comparison.ToRuns)
+	}
+	if len(comparison.NewFailures) != 1 || comparison.NewFailures[0].Spec != "driver ready" {
+		t.Errorf("unexpected new failures: %+v", comparison.NewFailures)
+	}
+	if len(comparison.Fixed) != 1 || comparison.Fixed[0].Spec != "dcgm ready" {
+		t.Errorf("unexpected fixed specs: %+v", comparison.Fixed)
+	}
+	if len(comparison.StillFailing) != 1 || comparison.StillFailing[0].Spec != "toolkit ready" {
+		t.Errorf("unexpected still failing specs: %+v", comparison.StillFailing)
+	}
+	if len(comparison.Stages) != 1 || comparison.Stages[0].From.Mean != 10*time.Minute || comparison.Stages[0].To.Mean != 15*time.Minute {
+		t.Errorf("unexpected stage durations: %+v", comparison.Stages)
+	}
+	markdown := new(bytes.Buffer)
+	comparison.WriteMarkdown(markdown)
+	if !strings.Contains(markdown.String(), "| wait_for_gpu_operator | 10m0s | 15m0s | +5m0s (+50%) |") {
+		t.Errorf("unexpected markdown:\n%v", markdown)
+	}
+
+	trend := new(bytes.Buffer)
+	WriteStageTrend(trend, FilterHistory(records, HistoryFilter{OcpVersion: "4.14"}), "wait_for_gpu_operator")
+	if lines := strings.Split(strings.TrimSpace(trend.String()), "\n"); len(lines) != 6 {
+		t.Errorf("expected a row per 4.14 run:\n%v", trend)
+	}
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"timestamp": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+	"status": func(passed bool) string {
+		if passed {
+			return "passed"
+		}
+		return "failed"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GPU Operator CI run summary</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { white-space: pre-wrap; margin: 0; }
+.passed { color: #080; }
+.failed { color: #c00; }
+</style>
+</head>
+<body>
+<h1>GPU Operator CI run summary: <span class="{{status .Passed}}">{{status .Passed}}</span></h1>
+<table>
+<tr><th>OCP version</th><td>{{.OcpVersion}}</td></tr>
+<tr><th>GPU Operator version</th><td>{{.OperatorVersion}}</td></tr>
+<tr><th>GPU Operator channel</th><td>{{.Channel}}</td></tr>
+<tr><th>Return code</th><td>{{.ReturnCode}}</td></tr>
+{{- if .FailMessage}}
+<tr><th>Failure</th><td class="failed">{{.FailMessage}}</td></tr>
+{{- end}}
+<tr><th>Generated at</th><td>{{timestamp .GeneratedAt}}</td></tr>
+</table>
+
+<h2>Stages</h2>
+<table>
+<tr><th>Stage</th><th>Status</th><th>Started</th><th>Duration</th><th>Passed</th><th>Failed</th><th>Skipped</th></tr>
+{{- range .Stages}}
+<tr>
+<td><a href="#{{.Dir}}">{{.Name}}</a></td>
+<td class="{{status .Passed}}">{{status .Passed}}</td>
+<td>{{timestamp .StartTime}}</td>
+<td>{{duration .Duration}}</td>
+<td>{{.Specs.Passed}}</td>
+<td>{{.Specs.Failed}}</td>
+<td>{{.Specs.Skipped}}</td>
+</tr>
+{{- end}}
+</table>
+
+{{- range .Stages}}
+<h2 id="{{.Dir}}">{{.Name}}</h2>
+{{- range .Failures}}
+<h3 class="failed">{{.State}}: {{.Spec}}</h3>
+<table>
+<tr><th>Message</th><td><pre>{{.Message}}</pre></td></tr>
+<tr><th>Location</th><td>{{.Location}}</td></tr>
+{{- range .Diagnostics}}
+<tr><th>Diagnostics</th><td><a href="{{.}}">{{.}}</a></td></tr>
+{{- end}}
+</table>
+{{- if .Entries}}
+<table>
+<tr><th>Time</th><th>Entry</th><th>Value</th></tr>
+{{- range .Entries}}
+<tr><td>{{timestamp .Time}}</td><td>{{.Name}}</td><td><pre>{{.Value}}</pre></td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- if .Milestones}}
+<table>
+<tr><th>Milestone</th><th>Time</th><th>Duration</th><th>Budget</th><th>Source</th></tr>
+{{- range .Milestones}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{timestamp .Time}}</td>
+<td{{if .Exceeded}} class="failed"{{end}}>{{duration .Duration}}</td>
+<td>{{if .Budget}}{{duration .Budget}}{{end}}</td>
+<td>{{.Source}}</td>
+</tr>
+{{- end}}
+</table>
+{{- end}}
+<ul>
+{{- range .Artifacts}}
+<li><a href="{{.}}">{{.}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+// RenderHtml writes the summary as a static HTML page, with links relative to
+// the artifact dir.
+func RenderHtml(w io.Writer, summary *RunSummary) error {
+	return htmlTemplate.Execute(w, summary)
+}
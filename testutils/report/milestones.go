@@ -0,0 +1,88 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	MilestonesFile       = "milestones.json"
+	MilestoneMetricsFile = "milestones.prom"
+)
+
+// Milestone is a step timed from the start of a suite operation, e.g. the
+// GPU operator install from the creation of its Subscription.
+type Milestone struct {
+	Name     string        `json:"name"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Source tells where the time comes from: an object timestamp, or the
+	// time a test observed the milestone, which is an upper bound.
+	Source string        `json:"source"`
+	Budget time.Duration `json:"budget,omitempty"`
+}
+
+func (m Milestone) Exceeded() bool {
+	return m.Budget > 0 && m.Duration > m.Budget
+}
+
+// ParseBudgets parses comma-separated milestone budgets, e.g.
+// "driver_ready=15m,validator_ready=20m".
+func ParseBudgets(budgets string) (map[string]time.Duration, error) {
+	parsed := map[string]time.Duration{}
+	for _, budget := range strings.Split(budgets, ",") {
+		budget = strings.TrimSpace(budget)
+		if len(budget) == 0 {
+			continue
+		}
+		name, value, ok := strings.Cut(budget, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid milestone budget %q, expected <milestone>=<duration>", budget)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid milestone budget %q: %v", budget, err)
+		}
+		parsed[strings.TrimSpace(name)] = d
+	}
+	return parsed, nil
+}
+
+// FormatMilestoneMetrics formats the milestones in the Prometheus text
+// format, to be pushed or scraped by the CI dashboards.
+func FormatMilestoneMetrics(milestones []Milestone) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintln(buf, "# HELP gpu_operator_ci_milestone_seconds Seconds from the start of the timed operation, e.g. the GPU operator Subscription creation, to the milestone.")
+	fmt.Fprintln(buf, "# TYPE gpu_operator_ci_milestone_seconds gauge")
+	for _, m := range milestones {
+		fmt.Fprintf(buf, "gpu_operator_ci_milestone_seconds{milestone=%q,source=%q} %v\n", m.Name, m.Source, m.Duration.Seconds())
+	}
+	fmt.Fprintln(buf, "# HELP gpu_operator_ci_milestone_budget_seconds Configured budget of the milestone.")
+	fmt.Fprintln(buf, "# TYPE gpu_operator_ci_milestone_budget_seconds gauge")
+	for _, m := range milestones {
+		if m.Budget > 0 {
+			fmt.Fprintf(buf, "gpu_operator_ci_milestone_budget_seconds{milestone=%q} %v\n", m.Name, m.Budget.Seconds())
+		}
+	}
+	return buf.Bytes()
+}
+
+func readMilestones(artifactDir string, dir string) ([]Milestone, error) {
+	data, err := os.ReadFile(filepath.Join(artifactDir, dir, MilestonesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	milestones := []Milestone{}
+	if err := json.Unmarshal(data, &milestones); err != nil {
+		return nil, fmt.Errorf("invalid milestones in %v: %v", dir, err)
+	}
+	return milestones, nil
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"ci-tools-nvidia-gpu-operator/testutils/metrics"
+)
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets("driver_ready=15m, validator_ready=20m,")
+	if err != nil {
+		t.Fatalf("ParseBudgets failed: %v", err)
+	}
+	if len(budgets) != 2 || budgets["driver_ready"] != 15*time.Minute || budgets["validator_ready"] != 20*time.Minute {
+		t.Errorf("unexpected budgets: %v", budgets)
+	}
+	for _, invalid := range []string{"driver_ready", "driver_ready=15"} {
+		if _, err := ParseBudgets(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestFormatMilestoneMetrics(t *testing.T) {
+	milestones := []Milestone{
+		{Name: "csv_succeeded", Duration: 90 * time.Second, Source: "CSV condition"},
+		{Name: "driver_ready", Duration: 16 * time.Minute, Source: "pod condition", Budget: 15 * time.Minute},
+	}
+	if milestones[0].Exceeded() || !milestones[1].Exceeded() {
+		t.Errorf("only driver_ready should exceed its budget")
+	}
+	families, err := metrics.Parse(string(FormatMilestoneMetrics(milestones)))
+	if err != nil {
+		t.Fatalf("invalid metrics: %v", err)
+	}
+	driver := families.Samples("gpu_operator_ci_milestone_seconds", map[string]string{"milestone": "driver_ready"})
+	if len(driver) != 1 || driver[0].Value != 960 || driver[0].Labels["source"] != "pod condition" {
+		t.Errorf("unexpected driver_ready samples: %+v", driver)
+	}
+	budgets := families.Samples("gpu_operator_ci_milestone_budget_seconds", nil)
+	if len(budgets) != 1 || budgets[0].Value != 900 {
+		t.Errorf("expected a single budget sample, got %+v", budgets)
+	}
+}
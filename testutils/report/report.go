@@ -0,0 +1,246 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+const (
+	SummaryFile        = "run-summary.json"
+	HtmlFile           = "run-summary.html"
+	jsonReportPrefix   = "report_"
+	DiagnosticsDir     = "diagnostics"
+	maxSpecDirNameSize = 100
+)
+
+var (
+	// Stage directories are created by hack/run_test.sh as <timestamp>_<stage>
+	stageDirRegex    = regexp.MustCompile(`^[0-9]{10}_(.+)$`)
+	specDirNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// RunSummary aggregates the stages run by hack/run_test.sh in an artifact dir.
+type RunSummary struct {
+	GeneratedAt     time.Time `json:"generated_at"`
+	OcpVersion      string    `json:"ocp_version,omitempty"`
+	OperatorVersion string    `json:"operator_version,omitempty"`
+	Channel         string    `json:"channel,omitempty"`
+	Passed          bool      `json:"passed"`
+	ReturnCode      string    `json:"return_code,omitempty"`
+	FailMessage     string    `json:"fail_message,omitempty"`
+	Stages          []Stage   `json:"stages"`
+}
+
+// Stage is a single run of a make target, read from its Ginkgo JSON report.
+type Stage struct {
+	Name       string        `json:"name"`
+	Dir        string        `json:"dir"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Duration   time.Duration `json:"duration"`
+	Passed     bool          `json:"passed"`
+	Specs      SpecCounts    `json:"specs"`
+	Results    []SpecResult  `json:"results"`
+	Failures   []Failure     `json:"failures,omitempty"`
+	Milestones []Milestone   `json:"milestones,omitempty"`
+	Artifacts  []string      `json:"artifacts"`
+}
+
+type SpecCounts struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// SpecResult is the state of a spec, used to compare runs.
+type SpecResult struct {
+	Spec     string          `json:"spec"`
+	State    types.SpecState `json:"state"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// Failure is a failed spec with the report entries added by testutils.Printf.
+type Failure struct {
+	Spec        string          `json:"spec"`
+	State       types.SpecState `json:"state"`
+	Message     string          `json:"message"`
+	Location    string          `json:"location"`
+	Entries     []Entry         `json:"entries,omitempty"`
+	Diagnostics []string        `json:"diagnostics,omitempty"`
+}
+
+type Entry struct {
+	Time  time.Time `json:"time"`
+	Name  string    `json:"name"`
+	Value string    `json:"value"`
+}
+
+// DiagnosticsDirName is the directory, under <stage>/diagnostics, where
+// testutils collects the diagnostics of a failed spec.
+func DiagnosticsDirName(specText string) string {
+	name := strings.Trim(specDirNameRegex.ReplaceAllString(specText, "_"), "_")
+	if len(name) > maxSpecDirNameSize {
+		name = name[:maxSpecDirNameSize]
+	}
+	return name
+}
+
+// Generate reads the stages and the marker files of artifactDir. Paths in
+// the summary are relative to artifactDir.
+func Generate(artifactDir string) (*RunSummary, error) {
+	summary := &RunSummary{
+		GeneratedAt:     time.Now(),
+		OcpVersion:      readMarker(artifactDir, "ocp.version"),
+		OperatorVersion: readMarker(artifactDir, "operator.version"),
+		Channel:         readMarker(artifactDir, "operator.channel"),
+		ReturnCode:      readMarker(artifactDir, "RETURN_CODE"),
+		FailMessage:     readMarker(artifactDir, "FAIL"),
+		Stages:          []Stage{},
+	}
+	entries, err := os.ReadDir(artifactDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		match := stageDirRegex.FindStringSubmatch(entry.Name())
+		if !entry.IsDir() || match == nil {
+			continue
+		}
+		stage, err := readStage(artifactDir, entry.Name(), match[1])
+		if err != nil {
+			return nil, err
+		}
+		if stage != nil {
+			summary.Stages = append(summary.Stages, *stage)
+		}
+	}
+	sort.SliceStable(summary.Stages, func(i, j int) bool {
+		return summary.Stages[i].StartTime.Before(summary.Stages[j].StartTime)
+	})
+	summary.Passed = len(summary.FailMessage) == 0 && len(summary.Stages) > 0
+	for _, stage := range summary.Stages {
+		summary.Passed = summary.Passed && stage.Passed
+	}
+	return summary, nil
+}
+
+func readMarker(artifactDir string, name string) string {
+	data, err := os.ReadFile(filepath.Join(artifactDir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readStage returns nil when the stage has no Ginkgo JSON report, e.g. when
+// it did not run to completion.
+func readStage(artifactDir string, dir string, name string) (*Stage, error) {
+	reportPath := filepath.Join(artifactDir, dir, fmt.Sprintf("%v%v.json", jsonReportPrefix, name))
+	data, err := os.ReadFile(reportPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reports := []types.Report{}
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, fmt.Errorf("invalid Ginkgo report %v: %v", reportPath, err)
+	}
+	stage := &Stage{Name: name, Dir: dir, Passed: true, Results: []SpecResult{}}
+	for _, report := range reports {
+		if stage.StartTime.IsZero() || report.StartTime.Before(stage.StartTime) {
+			stage.StartTime = report.StartTime
+		}
+		if report.EndTime.After(stage.EndTime) {
+			stage.EndTime = report.EndTime
+		}
+		stage.Duration += report.RunTime
+		stage.Passed = stage.Passed && report.SuiteSucceeded
+		for _, spec := range report.SpecReports {
+			addSpec(artifactDir, stage, spec)
+		}
+	}
+	stage.Milestones, err = readMilestones(artifactDir, dir)
+	if err != nil {
+		return nil, err
+	}
+	stage.Artifacts, err = listArtifacts(artifactDir, dir)
+	if err != nil {
+		return nil, err
+	}
+	return stage, nil
+}
+
+func addSpec(artifactDir string, stage *Stage, spec types.SpecReport) {
+	text := spec.FullText()
+	if len(text) == 0 {
+		// Suite level nodes like BeforeSuite have no text
+		text = spec.LeafNodeType.String()
+	}
+	stage.Results = append(stage.Results, SpecResult{Spec: text, State: spec.State, Duration: spec.RunTime})
+	switch {
+	case spec.State.Is(types.SpecStatePassed):
+		stage.Specs.Passed++
+	case spec.State.Is(types.SpecStateFailureStates):
+		stage.Specs.Failed++
+		failure := Failure{
+			Spec:     text,
+			State:    spec.State,
+			Message:  spec.Failure.Message,
+			Location: spec.Failure.Location.String(),
+		}
+		for _, entry := range spec.ReportEntries {
+			failure.Entries = append(failure.Entries, Entry{Time: entry.Time, Name: entry.Name, Value: entry.StringRepresentation()})
+		}
+		diagnostics := filepath.Join(stage.Dir, DiagnosticsDir, DiagnosticsDirName(spec.FullText()))
+		if info, err := os.Stat(filepath.Join(artifactDir, diagnostics)); err == nil && info.IsDir() {
+			failure.Diagnostics = append(failure.Diagnostics, diagnostics)
+		}
+		stage.Failures = append(stage.Failures, failure)
+	default:
+		stage.Specs.Skipped++
+	}
+}
+
+// listArtifacts returns the files of the stage directory, relative to the
+// artifact dir, without the diagnostics that are linked from the failures.
+func listArtifacts(artifactDir string, dir string) ([]string, error) {
+	artifacts := []string{}
+	entries, err := os.ReadDir(filepath.Join(artifactDir, dir))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		artifacts = append(artifacts, filepath.Join(dir, entry.Name()))
+	}
+	return artifacts, nil
+}
+
+// Write writes the summary as JSON and HTML into artifactDir.
+func Write(artifactDir string, summary *RunSummary) error {
+	data, err := json.MarshalIndent(summary, "", " ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(artifactDir, SummaryFile), data, 0644); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(artifactDir, HtmlFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return RenderHtml(f, summary)
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+func writeStage(t *testing.T, artifactDir string, dir string, name string, reports []types.Report) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(artifactDir, dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(artifactDir, dir, "report_"+name+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	artifactDir := t.TempDir()
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	failed := types.SpecReport{
+		ContainerHierarchyTexts: []string{"wait_for_gpu_operator :"},
+		LeafNodeText:            "ClusterPolicy should be ready",
+		LeafNodeType:            types.NodeTypeIt,
+		State:                   types.SpecStateFailed,
+		Failure: types.Failure{
+			Message:  "Timed out",
+			Location: types.NewCodeLocation(0),
+		},
+		ReportEntries: types.ReportEntries{{Name: "Retry", Time: start}},
+	}
+	writeStage(t, artifactDir, "1682935300_wait_for_gpu_operator", "wait_for_gpu_operator", []types.Report{{
+		StartTime: start.Add(time.Minute),
+		EndTime:   start.Add(3 * time.Minute),
+		RunTime:   2 * time.Minute,
+		SpecReports: types.SpecReports{
+			{LeafNodeType: types.NodeTypeIt, State: types.SpecStatePassed},
+			failed,
+			{LeafNodeType: types.NodeTypeIt, State: types.SpecStateSkipped},
+		},
+	}})
+	writeStage(t, artifactDir, "1682935200_test_ocp_connection", "test_ocp_connection", []types.Report{{
+		SuiteSucceeded: true,
+		StartTime:      start,
+		EndTime:        start.Add(time.Minute),
+		RunTime:        time.Minute,
+		SpecReports:    types.SpecReports{{LeafNodeType: types.NodeTypeIt, State: types.SpecStatePassed}},
+	}})
+	diagnostics := filepath.Join("1682935300_wait_for_gpu_operator", DiagnosticsDir, DiagnosticsDirName(failed.FullText()))
+	if err := os.MkdirAll(filepath.Join(artifactDir, diagnostics), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A stage interrupted before writing its report is ignored
+	if err := os.MkdirAll(filepath.Join(artifactDir, "1682935400_run_gpu_workload"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"ocp.version": "4.12.10\n", "FAIL": "wait_for_gpu_operator Test Failed.\n", "RETURN_CODE": "11\n"} {
+		if err := os.WriteFile(filepath.Join(artifactDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary, err := Generate(artifactDir)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if summary.Passed || summary.OcpVersion != "4.12.10" || summary.ReturnCode != "11" {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if len(summary.Stages) != 2 || summary.Stages[0].Name != "test_ocp_connection" || summary.Stages[1].Name != "wait_for_gpu_operator" {
+		t.Fatalf("expected the two stages sorted by start time, got %+v", summary.Stages)
+	}
+	stage := summary.Stages[1]
+	if stage.Passed || stage.Duration != 2*time.Minute || stage.Specs != (SpecCounts{Passed: 1, Failed: 1, Skipped: 1}) {
+		t.Errorf("unexpected stage: %+v", stage)
+	}
+	if len(stage.Failures) != 1 {
+		t.Fatalf("expected a single failure, got %+v", stage.Failures)
+	}
+	failure := stage.Failures[0]
+	if failure.Spec != "wait_for_gpu_operator : ClusterPolicy should be ready" || failure.Message != "Timed out" || len(failure.Entries) != 1 {
+		t.Errorf("unexpected failure: %+v", failure)
+	}
+	if len(failure.Diagnostics) != 1 || failure.Diagnostics[0] != diagnostics {
+		t.Errorf("expected diagnostics %v, got %v", diagnostics, failure.Diagnostics)
+	}
+	if len(stage.Artifacts) != 1 || stage.Artifacts[0] != filepath.Join(stage.Dir, "report_wait_for_gpu_operator.json") {
+		t.Errorf("unexpected artifacts: %v", stage.Artifacts)
+	}
+
+	html := new(bytes.Buffer)
+	if err := RenderHtml(html, summary); err != nil {
+		t.Fatalf("RenderHtml failed: %v", err)
+	}
+	for _, expected := range []string{"4.12.10", "ClusterPolicy should be ready", "Timed out", diagnostics} {
+		if !strings.Contains(html.String(), expected) {
+			t.Errorf("HTML report does not contain %q", expected)
+		}
+	}
+}
+
+func TestDiagnosticsDirName(t *testing.T) {
+	if name := DiagnosticsDirName("run_gpu_workload : gpu-burn [DaemonSet] should succeed"); name != "run_gpu_workload_gpu-burn_DaemonSet_should_succeed" {
+		t.Errorf("unexpected dir name %v", name)
+	}
+	if name := DiagnosticsDirName(strings.Repeat("a", 200)); len(name) != maxSpecDirNameSize {
+		t.Errorf("expected dir name truncated to %v, got %v", maxSpecDirNameSize, len(name))
+	}
+}